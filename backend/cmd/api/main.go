@@ -2,96 +2,259 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
+	"github.com/gowthamd/go-crud-app/internal/auth"
 	"github.com/gowthamd/go-crud-app/internal/config"
 	"github.com/gowthamd/go-crud-app/internal/db"
+	"github.com/gowthamd/go-crud-app/internal/events"
 	"github.com/gowthamd/go-crud-app/internal/handler"
+	"github.com/gowthamd/go-crud-app/internal/jobs"
+	"github.com/gowthamd/go-crud-app/internal/logging"
+	"github.com/gowthamd/go-crud-app/internal/metrics"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/ratelimit"
 	"github.com/gowthamd/go-crud-app/internal/repository"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+	"github.com/gowthamd/go-crud-app/internal/tracing"
 )
 
 func main() {
+	// Logs are JSON from the start; the level is adjustable at runtime.
+	level := new(slog.LevelVar)
+	slog.SetDefault(logging.New(os.Stdout, level))
+
 	// 1. Load Configuration
-	cfg, err := config.LoadConfig()
+	cfg, args, err := config.LoadConfig(os.Args[1:])
+	if config.IsHelp(err) {
+		return
+	}
+	var problems config.Problems
+	if errors.As(err, &problems) && len(args) == 2 && args[0] == "config" && args[1] == "print" {
+		// Show what was loaded alongside what is wrong with it.
+		cfg.Print(os.Stdout)
+	}
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		if problems != nil {
+			fatal("Invalid configuration", "problems", []string(problems))
+		}
+		fatal("Failed to load config", "error", err)
 	}
+	level.Set(cfg.LogLevel)
 
-	// 2. Initialize Database
-	database, err := db.New(cfg.DBUrl)
+	// Subcommands do their work and exit.
+	switch {
+	case len(args) == 0:
+	case args[0] == "migrate":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := runMigrate(ctx, cfg.DBUrl, args[1:], os.Stdout)
+		stop()
+		if err != nil {
+			fatal("Migration failed", "error", err)
+		}
+		return
+	case len(args) == 2 && args[0] == "config" && args[1] == "print":
+		cfg.Print(os.Stdout)
+		return
+	default:
+		fatal("Unknown command (want migrate or config print)", "command", strings.Join(args, " "))
+	}
+
+	// The settings were validated while loading.
+	priceFormat, _ := models.ParseMoneyJSONFormat(cfg.PriceFormat)
+	models.SetMoneyJSONFormat(priceFormat)
+	models.SetDefaultCurrency(cfg.DefaultCurrency)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName: "go-crud-app",
+		Exporter:    cfg.TracingExporter,
+		File:        cfg.TracingFile,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to set up tracing", "error", err)
 	}
-	defer database.Close()
 
-	// 3. Initialize Repositories & Handlers
-	itemRepo := repository.NewItemRepository(database.Pool)
-	itemHandler := handler.NewItemHandler(itemRepo)
-	healthHandler := handler.NewHealthHandler(database)
+	m := metrics.New()
 
-	// 4. Setup Router
-	r := chi.NewRouter()
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
-
-	// CORS Config
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   cfg.AllowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
-
-	// Routes
-	r.Get("/health", healthHandler.Liveness)
-	r.Get("/health/ready", healthHandler.Readiness)
-
-	r.Route("/api", func(r chi.Router) {
-		r.Route("/items", func(r chi.Router) {
-			r.Post("/", itemHandler.CreateItem)
-			r.Get("/", itemHandler.ListItems)
-			r.Get("/{id}", itemHandler.GetItem)
-			r.Put("/{id}", itemHandler.UpdateItem)
-			r.Delete("/{id}", itemHandler.DeleteItem)
+	// 2. Initialize Store
+	var (
+		itemStore    repository.ItemStore
+		webhookStore repository.WebhookStore
+		keyStore     repository.APIKeyStore
+		bucketStore  ratelimit.Store = ratelimit.NewMemoryStore()
+		pinger       handler.Pinger
+	)
+	switch cfg.Store {
+	case "postgres":
+		if cfg.MigrateOnStart {
+			if err := migrateUp(context.Background(), cfg.DBUrl); err != nil {
+				fatal("Migration failed", "error", err)
+			}
+		}
+		database, err := db.New(cfg.DBUrl, db.Options{
+			MaxConns:        cfg.DBMaxConns,
+			MinConns:        cfg.DBMinConns,
+			MaxConnLifetime: cfg.DBMaxConnLifetime,
+			MaxConnIdleTime: cfg.DBMaxConnIdleTime,
+			ConnectTimeout:  cfg.DBConnectTimeout,
 		})
-	})
+		if err != nil {
+			fatal("Failed to connect to database", "error", err)
+		}
+		defer database.Close()
+		m.Registry.MustRegister(metrics.NewPoolCollector(database))
+
+		repo := repository.NewItemRepository(database.Pool)
+		itemStore, webhookStore, keyStore = repo, repo, repo
+		if cfg.RateLimitStore == "postgres" {
+			bucketStore = repo
+		}
+		pinger = database
+	case "memory":
+		slog.Warn("Using in-memory item store; data will not survive a restart")
+		memStore := repository.NewMemoryItemRepository()
+		itemStore, webhookStore, keyStore = memStore, memStore, memStore
+		pinger = memStore
+	}
+	m.Registry.MustRegister(metrics.NewItemCollector(itemStore))
+
+	// Background jobs stop when the server shuts down.
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	if cfg.TrashRetention > 0 {
+		go jobs.NewPurgeJob(itemStore, cfg.TrashRetention, cfg.TrashPurgeInterval).Run(jobsCtx)
+	}
+
+	go jobs.NewWebhookDispatcher(webhookStore, cfg.WebhookPollInterval, cfg.WebhookMaxAttempts, cfg.WebhookAllowPrivate).Run(jobsCtx)
+
+	broker := events.NewBroker()
+	go broker.Run(jobsCtx, itemStore)
+
+	var verifier *auth.Verifier
+	var policy auth.Policy
+	if cfg.AuthEnabled() {
+		opts := auth.Options{
+			HMACSecret: []byte(cfg.JWTSecret),
+			Issuer:     cfg.JWTIssuer,
+			Audience:   cfg.JWTAudience,
+			APIKeys:    keyStore,
+		}
+		if cfg.JWKSSource != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			opts.KeySet, err = auth.LoadKeySet(ctx, cfg.JWKSSource)
+			cancel()
+			if err != nil {
+				fatal("Failed to load JWT_JWKS", "error", err)
+			}
+		}
+		if verifier, err = auth.NewVerifier(opts); err != nil {
+			fatal("Failed to configure authentication", "error", err)
+		}
+
+		policy = auth.DefaultPolicy()
+		if cfg.RBACPolicyFile != "" {
+			if policy, err = auth.LoadPolicy(cfg.RBACPolicyFile); err != nil {
+				fatal("Failed to load RBAC_POLICY_FILE", "error", err)
+			}
+		}
+	} else {
+		slog.Warn("JWT_HS256_SECRET and JWT_JWKS are unset; the API accepts unauthenticated requests")
+	}
+
+	// 3. Initialize Handlers
+	itemHandler := handler.NewItemHandler(itemStore)
+	eventHandler := handler.NewEventHandler(itemStore, broker)
+	webhookHandler := handler.NewWebhookHandler(webhookStore)
+	apiKeyHandler := handler.NewAPIKeyHandler(keyStore, auth.Permissions, policy)
+	logHandler := handler.NewLogHandler(level)
+	healthHandler := handler.NewHealthHandler(pinger)
+
+	tenants := &tenant.Resolver{
+		BaseDomain: cfg.TenantBaseDomain,
+		MaxItems:   cfg.TenantMaxItems,
+		Quotas:     cfg.TenantQuotas,
+	}
+	if policy != nil {
+		tenants.CrossTenant = policy.CrossTenant
+	}
+
+	limiter := ratelimit.NewLimiter(bucketStore, cfg.RateLimitRead, cfg.RateLimitWrite, cfg.TrustedProxies)
+	limiter.IP = cfg.RateLimitIP
+	go limiter.Run(jobsCtx, time.Minute)
+
+	// 4. Setup Router
+	r := newRouter(cfg, m, verifier, policy, tenants, limiter, itemHandler, eventHandler, webhookHandler, apiKeyHandler, logHandler, healthHandler)
 
 	// 5. Start Server
 	srv := &http.Server{
-		Addr:    ":" + cfg.Port,
-		Handler: r,
+		Addr:              ":" + cfg.Port,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
 	}
 
 	// Graceful Shutdown
 	go func() {
-		log.Printf("Starting server on port %s", cfg.Port)
+		slog.Info("Starting server", "port", cfg.Port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed: %v", err)
+			fatal("Server failed", "error", err)
 		}
 	}()
 
+	// The admin server keeps metrics and the log level off the public
+	// port.
+	var adminSrv *http.Server
+	if cfg.MetricsPort != "" {
+		adminMux := http.NewServeMux()
+		adminMux.Handle("GET /metrics", m.Handler())
+		adminMux.HandleFunc("GET /admin/log-level", logHandler.GetLogLevel)
+		adminMux.HandleFunc("PUT /admin/log-level", logHandler.SetLogLevel)
+		adminSrv = &http.Server{Addr: ":" + cfg.MetricsPort, Handler: adminMux, ReadHeaderTimeout: cfg.ReadHeaderTimeout}
+		go func() {
+			slog.Info("Serving metrics", "port", cfg.MetricsPort)
+			if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal("Metrics server failed", "error", err)
+			}
+		}()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
+	stopJobs()
+	// End change feed streams so Shutdown does not wait on them; clients
+	// reconnect to another replica.
+	broker.Close()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		fatal("Server forced to shutdown", "error", err)
+	}
+	if adminSrv != nil {
+		adminSrv.Shutdown(ctx)
+	}
+	// Flush the spans of the last requests.
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
 	}
 
-	log.Println("Server exited properly")
+	slog.Info("Server exited properly")
+}
+
+// fatal logs msg at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/internal/migrate"
+	"github.com/gowthamd/go-crud-app/migrations"
+	"github.com/jackc/pgx/v5"
+)
+
+const migrateUsage = "usage: api migrate up | down | status | to <version> | baseline <version>"
+
+// runMigrate runs the migrate subcommand with args against the database
+// at dbURL, writing status reports to out.
+func runMigrate(ctx context.Context, dbURL string, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	var version int64
+	switch args[0] {
+	case "up", "down", "status":
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+	case "to", "baseline":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		var err error
+		if version, err = strconv.ParseInt(args[1], 10, 64); err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	m, closeConn, err := newMigrator(ctx, dbURL)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	var n int
+	switch args[0] {
+	case "up":
+		n, err = m.Up(ctx)
+	case "down":
+		n, err = m.Down(ctx)
+	case "to":
+		n, err = m.To(ctx, version)
+	case "baseline":
+		if err = m.Baseline(ctx, version); err == nil {
+			slog.Info("Recorded migrations as applied", "version", version)
+		}
+		return err
+	case "status":
+		return writeMigrationStatus(ctx, m, out)
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("Migrations complete", "count", n)
+	return nil
+}
+
+// migrateUp applies every pending migration, for -migrate at startup.
+func migrateUp(ctx context.Context, dbURL string) error {
+	m, closeConn, err := newMigrator(ctx, dbURL)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	n, err := m.Up(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("Migrations complete", "count", n)
+	return nil
+}
+
+// newMigrator connects outside the pool: closing the connection releases
+// the migration lock even if unlocking fails.
+func newMigrator(ctx context.Context, dbURL string) (*migrate.Migrator, func(), error) {
+	all, err := migrate.Load(migrations.FS)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := pgx.Connect(ctx, dbURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to connect to database: %w", err)
+	}
+	return migrate.New(conn, all), func() { conn.Close(context.Background()) }, nil
+}
+
+func writeMigrationStatus(ctx context.Context, m *migrate.Migrator, out io.Writer) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRunMigrate_Usage(t *testing.T) {
+	// Invalid commands are refused before connecting.
+	for _, args := range [][]string{nil, {"sideways"}, {"up", "3"}, {"to"}, {"to", "x"}, {"baseline", "-1"}} {
+		err := runMigrate(context.Background(), "postgres://invalid:1/none", args, io.Discard)
+		if err == nil || strings.Contains(err.Error(), "connect") {
+			t.Errorf("%v: expected a usage error, got %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+	"github.com/gowthamd/go-crud-app/internal/auth"
+	"github.com/gowthamd/go-crud-app/internal/config"
+	"github.com/gowthamd/go-crud-app/internal/handler"
+	"github.com/gowthamd/go-crud-app/internal/identity"
+	"github.com/gowthamd/go-crud-app/internal/logging"
+	"github.com/gowthamd/go-crud-app/internal/metrics"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+	"github.com/gowthamd/go-crud-app/internal/ratelimit"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+	"github.com/gowthamd/go-crud-app/internal/tracing"
+)
+
+// eventsPath is the change feed endpoint. EventSource cannot send an
+// Authorization header, so it may pass its token as access_token.
+const eventsPath = "/api/items/events"
+
+// newRouter wires the API. A nil verifier leaves the API unauthenticated,
+// a nil policy lets every caller use every route and a nil limiter does
+// not throttle; tenants resolves the tenant every API request works on.
+// Requests are recorded in m, which is served on /metrics unless
+// cfg.MetricsPort moves it to an admin server. Every request is traced
+// and logged as JSON by the default slog logger.
+func newRouter(cfg *config.Config, m *metrics.Metrics, verifier *auth.Verifier, policy auth.Policy, tenants *tenant.Resolver, limiter *ratelimit.Limiter, itemHandler *handler.ItemHandler, eventHandler *handler.EventHandler, webhookHandler *handler.WebhookHandler, apiKeyHandler *handler.APIKeyHandler, logHandler *handler.LogHandler, healthHandler *handler.HealthHandler) http.Handler {
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Use(tracing.Middleware)
+	r.Use(middleware.RequestID)
+	r.Use(requestIDHeader)
+	r.Use(logging.Middleware(slog.Default()))
+	r.Use(middleware.Recoverer)
+
+	// CORS Config
+	r.Use(cors.Handler(cors.Options{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "If-None-Match", "Last-Event-ID", "X-API-Key", "X-Tenant-ID", "traceparent", "tracestate"},
+		ExposedHeaders:   []string{"Link", "ETag", "X-Request-Id", "WWW-Authenticate", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"},
+		AllowCredentials: true,
+		MaxAge:           int(cfg.CORSMaxAge.Seconds()),
+	}))
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		problem.Error(w, r, http.StatusNotFound, "No route matches "+r.URL.Path)
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		problem.Error(w, r, http.StatusMethodNotAllowed, r.Method+" is not supported on "+r.URL.Path)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(cfg.RequestTimeout))
+		r.Get("/health", healthHandler.Liveness)
+		r.Get("/health/ready", healthHandler.Readiness)
+		if cfg.MetricsPort == "" {
+			r.Method(http.MethodGet, "/metrics", m.Handler())
+		}
+	})
+
+	require := func(perm string) func(http.Handler) http.Handler {
+		if policy == nil {
+			return func(next http.Handler) http.Handler { return next }
+		}
+		return policy.Require(perm)
+	}
+
+	r.Group(func(r chi.Router) {
+		if verifier != nil {
+			// Bad credentials never reach the identity-keyed limiter, so
+			// every request is first charged to its IP.
+			if limiter != nil {
+				r.Use(limiter.IPMiddleware)
+			}
+			r.Use(verifier.Middleware(eventsPath))
+		}
+		r.Use(tenants.Middleware)
+		r.Use(logCaller)
+		if limiter != nil {
+			r.Use(limiter.Middleware)
+		}
+
+		// The change feed stays open indefinitely and an export streams the
+		// whole catalog, so both are registered outside the request timeout.
+		r.With(require(auth.PermItemsRead)).Get(eventsPath, eventHandler.StreamItemEvents)
+		r.With(require(auth.PermItemsRead)).Get("/api/items/export", itemHandler.ExportItems)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(cfg.RequestTimeout))
+
+			r.Route("/api/items", func(r chi.Router) {
+				r.Group(func(r chi.Router) {
+					r.Use(require(auth.PermItemsRead))
+					r.Get("/", itemHandler.ListItems)
+					r.Get("/search", itemHandler.SearchItems)
+					r.Get("/{id}", itemHandler.GetItem)
+					r.Get("/{id}/history", itemHandler.GetItemHistory)
+				})
+				r.Group(func(r chi.Router) {
+					r.Use(require(auth.PermItemsWrite))
+					r.Post("/", itemHandler.CreateItem)
+					r.Put("/{id}", itemHandler.UpdateItem)
+					r.Post("/{id}/restore", itemHandler.RestoreItem)
+					r.Post("/{id}/revisions/{version}/revert", itemHandler.RevertItem)
+				})
+				r.With(require(auth.PermItemsDelete)).Delete("/{id}", itemHandler.DeleteItem)
+				r.Group(func(r chi.Router) {
+					r.Use(require(auth.PermItemsBulk))
+					r.Post("/bulk", itemHandler.BulkItems)
+					r.Post("/import", itemHandler.ImportItems)
+				})
+			})
+
+			r.Route("/api/webhooks", func(r chi.Router) {
+				r.Use(require(auth.PermWebhooksManage))
+				r.Post("/", webhookHandler.CreateWebhook)
+				r.Get("/", webhookHandler.ListWebhooks)
+				r.Get("/{id}", webhookHandler.GetWebhook)
+				r.Put("/{id}", webhookHandler.UpdateWebhook)
+				r.Delete("/{id}", webhookHandler.DeleteWebhook)
+				r.Get("/{id}/deliveries", webhookHandler.ListDeliveries)
+				r.Post("/{id}/deliveries/{delivery}/redeliver", webhookHandler.Redeliver)
+			})
+
+			r.Route("/api/keys", func(r chi.Router) {
+				r.Use(require(auth.PermKeysManage))
+				r.Post("/", apiKeyHandler.CreateAPIKey)
+				r.Get("/", apiKeyHandler.ListAPIKeys)
+				r.Delete("/{id}", apiKeyHandler.DeleteAPIKey)
+			})
+
+			r.Route("/api/admin/log-level", func(r chi.Router) {
+				r.Use(require(auth.PermLogsManage))
+				r.Get("/", logHandler.GetLogLevel)
+				r.Put("/", logHandler.SetLogLevel)
+			})
+		})
+	})
+
+	return r
+}
+
+// logCaller adds the authenticated caller and tenant to the request log.
+func logCaller(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.AddAttrs(r.Context(), "user", identity.Actor(r.Context()), "tenant", tenant.ID(r.Context()))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestIDHeader echoes the request id so clients can quote it when
+// reporting a problem response.
+func requestIDHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(r.Context()))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,1021 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/auth"
+	"github.com/gowthamd/go-crud-app/internal/config"
+	"github.com/gowthamd/go-crud-app/internal/events"
+	"github.com/gowthamd/go-crud-app/internal/handler"
+	"github.com/gowthamd/go-crud-app/internal/metrics"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+	"github.com/gowthamd/go-crud-app/internal/repository"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+	"github.com/gowthamd/go-crud-app/internal/tracing"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return newAuthTestServer(t, nil, nil)
+}
+
+// newAuthTestServer starts a server that authenticates with opts, backed
+// by its store for API keys, and authorizes with policy. Tenant "small"
+// has a quota of one item.
+func newAuthTestServer(t *testing.T, opts *auth.Options, policy auth.Policy) *httptest.Server {
+	t.Helper()
+	store := repository.NewMemoryItemRepository()
+	var verifier *auth.Verifier
+	if opts != nil {
+		opts.APIKeys = store
+		var err error
+		if verifier, err = auth.NewVerifier(*opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := config.Default()
+	broker := events.NewBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	go broker.Run(ctx, store)
+	t.Cleanup(cancel)
+
+	tenants := &tenant.Resolver{Quotas: map[string]int{"small": 1}}
+	if policy != nil {
+		tenants.CrossTenant = policy.CrossTenant
+	}
+	m := metrics.New()
+	m.Registry.MustRegister(metrics.NewItemCollector(store))
+	srv := httptest.NewServer(newRouter(cfg, m, verifier, policy, tenants, nil, handler.NewItemHandler(store), handler.NewEventHandler(store, broker), handler.NewWebhookHandler(store), handler.NewAPIKeyHandler(store, auth.Permissions, policy), handler.NewLogHandler(new(slog.LevelVar)), handler.NewHealthHandler(store)))
+	t.Cleanup(srv.Close)
+	t.Cleanup(broker.Close)
+	return srv
+}
+
+// doRequest sends a JSON request; headers are optional key/value pairs.
+func doRequest(t *testing.T, method, url, body string, headers ...string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestRouter_ItemLifecycle(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Widget","price":9.99}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d", resp.StatusCode)
+	}
+	var created models.Item
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items/"+created.ID.String(), "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("get: expected 200, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodPut, srv.URL+"/api/items/"+created.ID.String(), `{"price":19.99}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update: expected 200, got %d", resp.StatusCode)
+	}
+	var updated models.Item
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "Widget" || !updated.Price.Equal(models.MustParseMoney("19.99")) {
+		t.Errorf("unexpected updated item %+v", updated)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items?limit=10", "")
+	var page models.PaginatedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || len(page.Items) != 1 {
+		t.Errorf("expected one listed item, got %+v", page)
+	}
+
+	resp = doRequest(t, http.MethodDelete, srv.URL+"/api/items/"+created.ID.String(), "")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items/"+created.ID.String(), "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("get after delete: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestRouter_Health(t *testing.T) {
+	srv := newTestServer(t)
+
+	for _, path := range []string{"/health", "/health/ready"} {
+		resp := doRequest(t, http.MethodGet, srv.URL+path, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestRouter_CursorPagination(t *testing.T) {
+	srv := newTestServer(t)
+
+	for i := 0; i < 3; i++ {
+		resp := doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Widget","price":1}`)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create: expected 201, got %d", resp.StatusCode)
+		}
+	}
+
+	resp := doRequest(t, http.MethodGet, srv.URL+"/api/items?cursor=&limit=2", "")
+	var page models.CursorPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.NextCursor == "" || page.PrevCursor != "" {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	link := resp.Header.Get("Link")
+	if !strings.Contains(link, `rel="next"`) || !strings.Contains(link, "cursor="+page.NextCursor) {
+		t.Errorf("expected next Link header, got %q", link)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items?limit=2&cursor="+page.NextCursor, "")
+	var page2 models.CursorPage
+	if err := json.NewDecoder(resp.Body).Decode(&page2); err != nil {
+		t.Fatal(err)
+	}
+	if len(page2.Items) != 1 || page2.NextCursor != "" || page2.PrevCursor == "" {
+		t.Fatalf("unexpected second page %+v", page2)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items?cursor=&sort=price", "")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 combining cursor and sort, got %d", resp.StatusCode)
+	}
+}
+
+func TestRouter_OptimisticConcurrency(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Widget","price":1}`)
+	var item models.Item
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		t.Fatal(err)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("expected ETag \"1\" on create, got %q", etag)
+	}
+	itemURL := srv.URL + "/api/items/" + item.ID.String()
+
+	resp = doRequest(t, http.MethodGet, itemURL, "", "If-None-Match", etag)
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("expected 304 for matching If-None-Match, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodPut, itemURL, `{"price":2}`, "If-Match", etag)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != `"2"` {
+		t.Fatalf("expected 200 with ETag \"2\", got %d %q", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+
+	// A second writer still holding the old ETag is rejected.
+	resp = doRequest(t, http.MethodPut, itemURL, `{"price":3}`, "If-Match", etag)
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for stale If-Match, got %d", resp.StatusCode)
+	}
+	resp = doRequest(t, http.MethodDelete, itemURL, "", "If-Match", etag)
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for stale delete, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodDelete, itemURL, "", "If-Match", `"1", "2"`)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected 204 when one of several tags matches, got %d", resp.StatusCode)
+	}
+}
+
+func TestRouter_TrashAndRestore(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Widget","price":1}`)
+	var item models.Item
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		t.Fatal(err)
+	}
+	itemURL := srv.URL + "/api/items/" + item.ID.String()
+
+	if resp := doRequest(t, http.MethodDelete, itemURL, ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", resp.StatusCode)
+	}
+
+	listTotal := func(query string) int {
+		resp := doRequest(t, http.MethodGet, srv.URL+"/api/items?"+query, "")
+		var page models.PaginatedResponse
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+		return page.Total
+	}
+	if n := listTotal(""); n != 0 {
+		t.Errorf("expected trashed item hidden by default, got %d", n)
+	}
+	if n := listTotal("deleted=only"); n != 1 {
+		t.Errorf("expected trashed item in trash listing, got %d", n)
+	}
+
+	resp = doRequest(t, http.MethodPost, itemURL+"/restore", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("restore: expected 200, got %d", resp.StatusCode)
+	}
+	if resp := doRequest(t, http.MethodGet, itemURL, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("get after restore: expected 200, got %d", resp.StatusCode)
+	}
+	if resp := doRequest(t, http.MethodPost, itemURL+"/restore", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("restoring a live item: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestRouter_HistoryAndRevert(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Widget","price":1}`)
+	var item models.Item
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		t.Fatal(err)
+	}
+	itemURL := srv.URL + "/api/items/" + item.ID.String()
+
+	if resp := doRequest(t, http.MethodPut, itemURL, `{"name":"Gadget"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("update: expected 200, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodGet, itemURL+"/history", "")
+	var history models.ItemHistory
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Revisions) != 2 || history.Revisions[0].Version != 2 {
+		t.Fatalf("expected two revisions newest first, got %+v", history.Revisions)
+	}
+
+	resp = doRequest(t, http.MethodGet, itemURL+"?as_of="+item.CreatedAt.Format(time.RFC3339Nano), "")
+	var old models.Item
+	if err := json.NewDecoder(resp.Body).Decode(&old); err != nil {
+		t.Fatal(err)
+	}
+	if old.Name != "Widget" {
+		t.Errorf("expected as_of view named Widget, got %q", old.Name)
+	}
+	if resp.Header.Get("ETag") != "" {
+		t.Error("expected no ETag on a historical view")
+	}
+	if resp := doRequest(t, http.MethodGet, itemURL+"?as_of=yesterday", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bad as_of: expected 400, got %d", resp.StatusCode)
+	}
+
+	if resp := doRequest(t, http.MethodPost, itemURL+"/revisions/1/revert", "", "If-Match", `"1"`); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("stale revert: expected 412, got %d", resp.StatusCode)
+	}
+	resp = doRequest(t, http.MethodPost, itemURL+"/revisions/1/revert", "", "If-Match", `"2"`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("revert: expected 200, got %d", resp.StatusCode)
+	}
+	var reverted models.Item
+	if err := json.NewDecoder(resp.Body).Decode(&reverted); err != nil {
+		t.Fatal(err)
+	}
+	if reverted.Name != "Widget" || resp.Header.Get("ETag") != `"3"` {
+		t.Errorf("expected version 3 named Widget, got %+v (ETag %s)", reverted, resp.Header.Get("ETag"))
+	}
+	if resp := doRequest(t, http.MethodPost, itemURL+"/revisions/42/revert", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown revision: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestRouter_Bulk(t *testing.T) {
+	srv := newTestServer(t)
+	bulkURL := srv.URL + "/api/items/bulk"
+	missing := "00000000-0000-0000-0000-000000000001"
+
+	batch := func(mode string) string {
+		return `{"mode":"` + mode + `","operations":[
+			{"op":"create","item":{"name":"Widget","price":1}},
+			{"op":"create","item":{"name":"","price":-1}},
+			{"op":"delete","id":"` + missing + `"}
+		]}`
+	}
+
+	resp := doRequest(t, http.MethodPost, bulkURL, batch("atomic"))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("atomic with invalid op: expected 400, got %d", resp.StatusCode)
+	}
+	var p problem.Details
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Errors) != 2 || p.Errors[0].Field != "operations[1].name" {
+		t.Errorf("expected errors for operation 1, got %+v", p.Errors)
+	}
+
+	resp = doRequest(t, http.MethodPost, bulkURL, batch("best_effort"))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("best effort: expected 200, got %d", resp.StatusCode)
+	}
+	var out models.BulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Succeeded != 1 || out.Failed != 2 {
+		t.Errorf("expected 1 succeeded and 2 failed, got %+v", out)
+	}
+	for i, want := range []int{http.StatusCreated, http.StatusBadRequest, http.StatusNotFound} {
+		if got := out.Results[i].Status; got != want {
+			t.Errorf("result %d: expected status %d, got %d", i, want, got)
+		}
+	}
+
+	resp = doRequest(t, http.MethodPost, bulkURL, `{"operations":[
+		{"op":"create","item":{"name":"Gadget","price":2}},
+		{"op":"delete","id":"`+missing+`"}
+	]}`)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("atomic with failing op: expected 404, got %d", resp.StatusCode)
+	}
+	list := doRequest(t, http.MethodGet, srv.URL+"/api/items", "")
+	var page models.PaginatedResponse
+	if err := json.NewDecoder(list.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 {
+		t.Errorf("expected the failed atomic batch to leave 1 item, got %d", page.Total)
+	}
+}
+
+func TestRouter_Export(t *testing.T) {
+	srv := newTestServer(t)
+	for _, body := range []string{
+		`{"name":"Widget","price":9.99,"prices":{"EUR":9.20}}`,
+		`{"name":"Gadget","price":5}`,
+		`{"name":"Gizmo","price":1}`,
+	} {
+		doRequest(t, http.MethodPost, srv.URL+"/api/items", body)
+	}
+
+	resp := doRequest(t, http.MethodGet, srv.URL+"/api/items/export?format=csv&sort=name&min_price=2", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("csv export: expected 200, got %d", resp.StatusCode)
+	}
+	if cd := resp.Header.Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="items-`) || !strings.HasSuffix(cd, `.csv"`) {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0][1] != "name" || records[1][1] != "Gadget" || records[2][1] != "Widget" {
+		t.Fatalf("unexpected CSV export %v", records)
+	}
+	if records[2][2] != "9.99" || records[2][4] != `{"EUR":9.20}` {
+		t.Errorf("unexpected price columns %v", records[2])
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items/export?format=json", "")
+	var items []models.Item
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Errorf("expected 3 items in JSON export, got %d", len(items))
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items/export?format=ndjson&name=nothing", "")
+	body, _ := io.ReadAll(resp.Body)
+	if resp.Header.Get("Content-Type") != "application/x-ndjson" || len(body) != 0 {
+		t.Errorf("expected empty NDJSON export, got %q", body)
+	}
+
+	if resp := doRequest(t, http.MethodGet, srv.URL+"/api/items/export?format=xlsx", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown format: expected 400, got %d", resp.StatusCode)
+	}
+}
+
+// deadlineStore records whether Export and GetAll run under a deadline.
+type deadlineStore struct {
+	*repository.MemoryItemRepository
+	export, list bool
+}
+
+func (s *deadlineStore) Export(ctx context.Context, filter *models.ItemFilter, sort []models.SortField, fn func(*models.Item) error) error {
+	_, s.export = ctx.Deadline()
+	return s.MemoryItemRepository.Export(ctx, filter, sort, fn)
+}
+
+func (s *deadlineStore) GetAll(ctx context.Context, query *models.ItemListQuery) ([]models.Item, int, error) {
+	_, s.list = ctx.Deadline()
+	return s.MemoryItemRepository.GetAll(ctx, query)
+}
+
+func TestRouter_ExportHasNoDeadline(t *testing.T) {
+	store := &deadlineStore{MemoryItemRepository: repository.NewMemoryItemRepository()}
+	cfg := config.Default()
+	srv := httptest.NewServer(newRouter(cfg, metrics.New(), nil, nil, &tenant.Resolver{}, nil, handler.NewItemHandler(store), handler.NewEventHandler(store, events.NewBroker()), handler.NewWebhookHandler(store), handler.NewAPIKeyHandler(store, auth.Permissions, nil), handler.NewLogHandler(new(slog.LevelVar)), handler.NewHealthHandler(store)))
+	t.Cleanup(srv.Close)
+
+	if resp := doRequest(t, http.MethodGet, srv.URL+"/api/items/export?format=ndjson", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("export: expected 200, got %d", resp.StatusCode)
+	}
+	if store.export {
+		t.Error("export should not run under the request timeout")
+	}
+	doRequest(t, http.MethodGet, srv.URL+"/api/items", "")
+	if !store.list {
+		t.Error("list should run under the request timeout")
+	}
+}
+
+func TestRouter_Search(t *testing.T) {
+	srv := newTestServer(t)
+	for _, body := range []string{
+		`{"name":"Blue Widget","price":9.99}`,
+		`{"name":"Red Gadget","price":5}`,
+	} {
+		doRequest(t, http.MethodPost, srv.URL+"/api/items", body)
+	}
+
+	resp := doRequest(t, http.MethodGet, srv.URL+"/api/items/search?q=widgit", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("search: expected 200, got %d", resp.StatusCode)
+	}
+	var found models.ItemSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&found); err != nil {
+		t.Fatal(err)
+	}
+	if found.Query != "widgit" || len(found.Results) != 1 || found.Results[0].Name != "Blue Widget" {
+		t.Fatalf("unexpected search response %+v", found)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items/search?q=gadget&max_price=1", "")
+	if err := json.NewDecoder(resp.Body).Decode(&found); err != nil {
+		t.Fatal(err)
+	}
+	if len(found.Results) != 0 {
+		t.Errorf("expected price filter to exclude Red Gadget, got %+v", found.Results)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items/search?q=%20&min_price=x", "")
+	var p problem.Details
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest || len(p.Errors) != 2 || p.Errors[0].Field != "q" {
+		t.Errorf("expected q and min_price errors, got %d %+v", resp.StatusCode, p.Errors)
+	}
+}
+
+func TestRouter_EventStream(t *testing.T) {
+	srv := newTestServer(t)
+	resp := doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Widget","price":9.99}`)
+	var created models.Item
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	// readEvent returns the id, event and data lines of the next message.
+	readEvent := func(t *testing.T, r *bufio.Reader) []string {
+		t.Helper()
+		var lines []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading event stream: %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				return lines
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	// A resuming client replays everything after Last-Event-ID and then
+	// receives live events.
+	stream := doRequest(t, http.MethodGet, srv.URL+"/api/items/events", "", "Last-Event-ID", "0")
+	if ct := stream.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+	r := bufio.NewReader(stream.Body)
+	if got := readEvent(t, r); len(got) != 3 || got[0] != "id: 1" || got[1] != "event: created" {
+		t.Fatalf("unexpected replayed event %q", got)
+	}
+
+	doRequest(t, http.MethodPut, srv.URL+"/api/items/"+created.ID.String(), `{"price":19.99}`)
+	got := readEvent(t, r)
+	if len(got) != 3 || got[0] != "id: 2" || got[1] != "event: updated" {
+		t.Fatalf("unexpected live event %q", got)
+	}
+	var event models.ItemEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(got[2], "data: ")), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.ItemID != created.ID || event.Version != 2 || !event.Snapshot.Price.Equal(models.MustParseMoney("19.99")) {
+		t.Errorf("unexpected event payload %+v", event)
+	}
+
+	if resp := doRequest(t, http.MethodGet, srv.URL+"/api/items/events", "", "Last-Event-ID", "abc"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid Last-Event-ID: expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestRouter_Webhooks(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := doRequest(t, http.MethodPost, srv.URL+"/api/webhooks", `{"url":"ftp://example.com","events":["item.renamed"]}`)
+	var p problem.Details
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest || len(p.Errors) != 2 {
+		t.Fatalf("expected url and events errors, got %d %+v", resp.StatusCode, p.Errors)
+	}
+
+	resp = doRequest(t, http.MethodPost, srv.URL+"/api/webhooks", `{"url":"https://example.com/hook","events":["item.created"]}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d", resp.StatusCode)
+	}
+	var hook models.Webhook
+	if err := json.NewDecoder(resp.Body).Decode(&hook); err != nil {
+		t.Fatal(err)
+	}
+	if len(hook.Secret) != 64 || !hook.Active {
+		t.Errorf("expected an active webhook with a generated secret, got %+v", hook)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/webhooks/"+hook.ID.String(), "")
+	var got models.Webhook
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Secret != "" {
+		t.Error("expected the secret to be withheld after creation")
+	}
+
+	doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Widget","price":1}`)
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/webhooks/"+hook.ID.String()+"/deliveries", "")
+	var deliveries []models.WebhookDelivery
+	if err := json.NewDecoder(resp.Body).Decode(&deliveries); err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].EventType != models.EventItemCreated || deliveries[0].Status != models.DeliveryPending {
+		t.Fatalf("expected one queued delivery, got %+v", deliveries)
+	}
+
+	url := fmt.Sprintf("%s/api/webhooks/%s/deliveries/%d/redeliver", srv.URL, hook.ID, deliveries[0].ID)
+	if resp := doRequest(t, http.MethodPost, url, ""); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("redeliver: expected 202, got %d", resp.StatusCode)
+	}
+	if resp := doRequest(t, http.MethodPost, srv.URL+"/api/webhooks/"+hook.ID.String()+"/deliveries/999/redeliver", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("redeliver unknown delivery: expected 404, got %d", resp.StatusCode)
+	}
+
+	if resp := doRequest(t, http.MethodPut, srv.URL+"/api/webhooks/"+hook.ID.String(), `{"active":false}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("update: expected 200, got %d", resp.StatusCode)
+	}
+	if resp := doRequest(t, http.MethodDelete, srv.URL+"/api/webhooks/"+hook.ID.String(), ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("delete: expected 204, got %d", resp.StatusCode)
+	}
+	if resp := doRequest(t, http.MethodGet, srv.URL+"/api/webhooks/"+hook.ID.String()+"/deliveries", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("deliveries of deleted webhook: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestRouter_Auth(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	srv := newAuthTestServer(t, &auth.Options{HMACSecret: secret}, nil)
+	bearer := "Bearer " + signTestToken(t, secret, "alice")
+
+	resp := doRequest(t, http.MethodGet, srv.URL+"/api/items", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("WWW-Authenticate"); got != "Bearer" {
+		t.Errorf("expected a Bearer challenge, got %q", got)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items", "", "Authorization", "Bearer garbage")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an invalid token, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/health", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected health checks to stay public, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Widget","price":1}`, "Authorization", bearer)
+	var item models.Item
+	json.NewDecoder(resp.Body).Decode(&item)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 with a token, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodGet, fmt.Sprintf("%s/api/items/%s/history", srv.URL, item.ID), "", "Authorization", bearer)
+	var history models.ItemHistory
+	json.NewDecoder(resp.Body).Decode(&history)
+	resp.Body.Close()
+	if len(history.Revisions) != 1 || history.Revisions[0].Actor != "alice" {
+		t.Fatalf("expected the revision to be attributed to alice, got %+v", history.Revisions)
+	}
+}
+
+// signTestToken issues an hour-long HS256 token for subject with roles.
+func signTestToken(t *testing.T, secret []byte, subject string, roles ...string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   subject,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": roles,
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRouter_RBAC(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	policy := auth.DefaultPolicy()
+	policy["operator"] = []string{auth.PermLogsManage}
+	srv := newAuthTestServer(t, &auth.Options{HMACSecret: secret}, policy)
+	viewer := "Bearer " + signTestToken(t, secret, "vic", "viewer")
+	editor := "Bearer " + signTestToken(t, secret, "eve", "editor")
+	admin := "Bearer " + signTestToken(t, secret, "ada", "admin")
+	operator := "Bearer " + signTestToken(t, secret, "opal", "operator")
+	nobody := "Bearer " + signTestToken(t, secret, "nob")
+
+	status := func(method, url, body, bearer string) int {
+		t.Helper()
+		resp := doRequest(t, method, url, body, "Authorization", bearer)
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusForbidden {
+			var p problem.Details
+			json.NewDecoder(resp.Body).Decode(&p)
+			if !strings.Contains(p.Detail, "Missing permission") {
+				t.Errorf("expected the 403 to name the missing permission, got %q", p.Detail)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	if got := status(http.MethodPost, srv.URL+"/api/items", `{"name":"Widget","price":1}`, viewer); got != http.StatusForbidden {
+		t.Fatalf("expected viewers not to create, got %d", got)
+	}
+	resp := doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Widget","price":1}`, "Authorization", editor)
+	var item models.Item
+	json.NewDecoder(resp.Body).Decode(&item)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected editors to create, got %d", resp.StatusCode)
+	}
+	itemURL := fmt.Sprintf("%s/api/items/%s", srv.URL, item.ID)
+
+	tests := []struct {
+		name, method, url, body, bearer string
+		want                            int
+	}{
+		{"no roles list", http.MethodGet, srv.URL + "/api/items", "", nobody, http.StatusForbidden},
+		{"viewer list", http.MethodGet, srv.URL + "/api/items", "", viewer, http.StatusOK},
+		{"viewer get", http.MethodGet, itemURL, "", viewer, http.StatusOK},
+		{"viewer update", http.MethodPut, itemURL, `{"name":"Gadget"}`, viewer, http.StatusForbidden},
+		{"editor update", http.MethodPut, itemURL, `{"name":"Gadget"}`, editor, http.StatusOK},
+		{"editor bulk", http.MethodPost, srv.URL + "/api/items/bulk", `{"operations":[]}`, editor, http.StatusForbidden},
+		{"editor webhooks", http.MethodGet, srv.URL + "/api/webhooks", "", editor, http.StatusForbidden},
+		{"admin webhooks", http.MethodGet, srv.URL + "/api/webhooks", "", admin, http.StatusOK},
+		{"editor delete", http.MethodDelete, itemURL, "", editor, http.StatusForbidden},
+		{"admin delete", http.MethodDelete, itemURL, "", admin, http.StatusNoContent},
+		{"editor log level", http.MethodGet, srv.URL + "/api/admin/log-level", "", editor, http.StatusForbidden},
+		{"admin log level", http.MethodGet, srv.URL + "/api/admin/log-level", "", admin, http.StatusForbidden},
+		{"operator log level", http.MethodGet, srv.URL + "/api/admin/log-level", "", operator, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := status(tt.method, tt.url, tt.body, tt.bearer); got != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRouter_APIKeys(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	srv := newAuthTestServer(t, &auth.Options{HMACSecret: secret}, auth.DefaultPolicy())
+	admin := "Bearer " + signTestToken(t, secret, "ada", "admin")
+
+	resp := doRequest(t, http.MethodPost, srv.URL+"/api/keys", `{"name":"nightly sync","scopes":["items:raed"]}`, "Authorization", admin)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown scope, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodPost, srv.URL+"/api/keys", `{"name":"nightly sync","scopes":["items:read"]}`, "Authorization", admin)
+	var created models.CreatedAPIKey
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	if !strings.HasPrefix(created.Key, created.Prefix+"_") || created.CreatedBy != "ada" {
+		t.Fatalf("unexpected key %+v", created)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items", "", "X-API-Key", created.Key)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the key to list items, got %d", resp.StatusCode)
+	}
+	resp = doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Widget","price":1}`, "X-API-Key", created.Key)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected a read-only key not to create, got %d", resp.StatusCode)
+	}
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items", "", "X-API-Key", created.Key+"x")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong key, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/keys", "", "Authorization", admin)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if strings.Contains(string(body), created.Key) {
+		t.Fatal("expected listings not to reveal the key")
+	}
+	var keys []models.APIKey
+	json.Unmarshal(body, &keys)
+	if len(keys) != 1 || keys[0].Prefix != created.Prefix || keys[0].LastUsedAt == nil {
+		t.Fatalf("expected one key with its last use recorded, got %s", body)
+	}
+
+	resp = doRequest(t, http.MethodDelete, srv.URL+"/api/keys/"+created.ID.String(), "", "Authorization", admin)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items", "", "X-API-Key", created.Key)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a revoked key to be rejected, got %d", resp.StatusCode)
+	}
+}
+
+func TestRouter_APIKeyScopes(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	policy := auth.DefaultPolicy()
+	policy["keymaster"] = []string{auth.PermKeysManage}
+	srv := newAuthTestServer(t, &auth.Options{HMACSecret: secret}, policy)
+	keymaster := "Bearer " + signTestToken(t, secret, "kim", "keymaster")
+
+	resp := doRequest(t, http.MethodPost, srv.URL+"/api/keys", `{"name":"escalate","scopes":["items:delete"]}`, "Authorization", keymaster)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for a scope the caller lacks, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodPost, srv.URL+"/api/keys", `{"name":"rotate","scopes":["keys:manage"]}`, "Authorization", keymaster)
+	var created models.CreatedAPIKey
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 for a scope the caller holds, got %d", resp.StatusCode)
+	}
+
+	// A key holding only keys:manage cannot widen itself either.
+	resp = doRequest(t, http.MethodPost, srv.URL+"/api/keys", `{"name":"escalate","scopes":["items:read"]}`, "X-API-Key", created.Key)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for a key minting a wider key, got %d", resp.StatusCode)
+	}
+}
+
+func TestRouter_Tenants(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Anvil","price":1}`, "X-Tenant-ID", "acme")
+	var item models.Item
+	json.NewDecoder(resp.Body).Decode(&item)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items/"+item.ID.String(), "", "X-Tenant-ID", "acme")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected acme to see its item, got %d", resp.StatusCode)
+	}
+	for _, tenantID := range []string{"beta", ""} {
+		resp = doRequest(t, http.MethodGet, srv.URL+"/api/items/"+item.ID.String(), "", "X-Tenant-ID", tenantID)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected tenant %q not to see acme's item, got %d", tenantID, resp.StatusCode)
+		}
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items", "", "X-Tenant-ID", "Not A Tenant")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a malformed tenant, got %d", resp.StatusCode)
+	}
+
+	// Tenant "small" may only hold one item.
+	resp = doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"First","price":1}`, "X-Tenant-ID", "small")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected the first item to fit the quota, got %d", resp.StatusCode)
+	}
+	resp = doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Second","price":1}`, "X-Tenant-ID", "small")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 past the quota, got %d", resp.StatusCode)
+	}
+}
+
+func TestRouter_TenantClaim(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	srv := newAuthTestServer(t, &auth.Options{HMACSecret: secret}, nil)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":    "ada",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"tenant": "acme",
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bearer := "Bearer " + token
+
+	resp := doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Anvil","price":1}`, "Authorization", bearer)
+	var item models.Item
+	json.NewDecoder(resp.Body).Decode(&item)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items/"+item.ID.String(), "", "Authorization", bearer, "X-Tenant-ID", "acme")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the token's tenant to see its item, got %d", resp.StatusCode)
+	}
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items", "", "Authorization", bearer, "X-Tenant-ID", "beta")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 when naming another tenant, got %d", resp.StatusCode)
+	}
+}
+
+func TestRouter_TenantHeader(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	policy := auth.DefaultPolicy()
+	policy["operator"] = []string{auth.PermItemsRead, auth.PermItemsWrite, auth.PermTenantsCross}
+	srv := newAuthTestServer(t, &auth.Options{HMACSecret: secret}, policy)
+	admin := "Bearer " + signTestToken(t, secret, "ada", "admin")
+	operator := "Bearer " + signTestToken(t, secret, "otto", "operator")
+
+	resp := doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Anvil","price":1}`, "Authorization", operator, "X-Tenant-ID", "beta")
+	var item models.Item
+	json.NewDecoder(resp.Body).Decode(&item)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected a cross-tenant caller to create in beta, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items/"+item.ID.String(), "", "Authorization", admin, "X-Tenant-ID", "beta")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected an unbound admin not to name beta, got %d", resp.StatusCode)
+	}
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items/"+item.ID.String(), "", "Authorization", admin)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected an unbound admin to stay in the default tenant, got %d", resp.StatusCode)
+	}
+}
+
+func TestRouter_Metrics(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := doRequest(t, http.MethodPost, srv.URL+"/api/items", `{"name":"Widget","price":1}`)
+	resp.Body.Close()
+	resp = doRequest(t, http.MethodGet, srv.URL+"/api/items/"+uuid.NewString(), "")
+	resp.Body.Close()
+	resp = doRequest(t, http.MethodGet, srv.URL+"/nowhere", "")
+	resp.Body.Close()
+
+	resp = doRequest(t, http.MethodGet, srv.URL+"/metrics", "")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	for _, want := range []string{
+		`http_requests_total{method="POST",route="/api/items",status="201"} 1`,
+		`http_requests_total{method="GET",route="/api/items/{id}",status="404"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="POST",route="/api/items",status="201"} 1`,
+		"items_live 1",
+		"items_created_last_minute 1",
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected the metrics to contain %s", want)
+		}
+	}
+}
+
+func TestRouter_Tracing(t *testing.T) {
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.ExporterNone, SampleRatio: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { shutdown(context.Background()) })
+	srv := newTestServer(t)
+
+	// Error responses quote the trace of the caller.
+	resp := doRequest(t, http.MethodGet, srv.URL+"/api/items/"+uuid.NewString(), "",
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	var p problem.Details
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNotFound || p.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected 404 in the caller's trace, got %d %+v", resp.StatusCode, p)
+	}
+
+	// Without a traceparent a new trace is started.
+	resp = doRequest(t, http.MethodGet, srv.URL+"/nowhere", "")
+	p = problem.Details{}
+	json.NewDecoder(resp.Body).Decode(&p)
+	if len(p.TraceID) != 32 || p.TraceID == "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected a new trace id, got %q", p.TraceID)
+	}
+}
+
+func TestRouter_LogLevel(t *testing.T) {
+	srv := newTestServer(t)
+	url := srv.URL + "/api/admin/log-level"
+
+	level := func(resp *http.Response) string {
+		t.Helper()
+		var body struct{ Level string }
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Level
+	}
+
+	resp := doRequest(t, http.MethodGet, url, "")
+	if resp.StatusCode != http.StatusOK || level(resp) != "info" {
+		t.Fatalf("expected the initial level, got %d", resp.StatusCode)
+	}
+	resp = doRequest(t, http.MethodPut, url, `{"level":"DEBUG"}`)
+	if resp.StatusCode != http.StatusOK || level(resp) != "debug" {
+		t.Fatalf("expected the level to change, got %d", resp.StatusCode)
+	}
+	resp = doRequest(t, http.MethodPut, url, `{"level":"verbose"}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected an unknown level to be refused, got %d", resp.StatusCode)
+	}
+	resp = doRequest(t, http.MethodGet, url, "")
+	if got := level(resp); got != "debug" {
+		t.Errorf("expected the level to persist, got %s", got)
+	}
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/identity"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/repository"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+)
+
+// HeaderAPIKey carries an API key in place of a bearer token.
+const HeaderAPIKey = "X-API-Key"
+
+// apiKeyTag starts every API key so that leaked keys are easy to spot.
+const apiKeyTag = "ik_"
+
+var (
+	errInvalidAPIKey = errors.New("invalid API key")
+	errExpiredAPIKey = errors.New("API key has expired")
+)
+
+// APIKeyStore looks up API keys by prefix and records their use.
+type APIKeyStore interface {
+	APIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	TouchAPIKey(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+// NewAPIKey generates a key of the form ik_<8 hex>_<43 base64url>. The
+// returned prefix is everything before the second underscore.
+func NewAPIKey() (key, prefix string) {
+	b := make([]byte, 4+32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	prefix = apiKeyTag + hex.EncodeToString(b[:4])
+	return prefix + "_" + base64.RawURLEncoding.EncodeToString(b[4:]), prefix
+}
+
+// HashAPIKey returns the digest stored in place of key.
+func HashAPIKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// apiKeyPrefix extracts the prefix of a well-formed key.
+func apiKeyPrefix(key string) (string, bool) {
+	if !strings.HasPrefix(key, apiKeyTag) {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(key[len(apiKeyTag):], "_")
+	if !ok || len(prefix) != 8 || secret == "" {
+		return "", false
+	}
+	return apiKeyTag + prefix, true
+}
+
+// VerifyAPIKey checks key against the stored keys and returns the caller
+// it identifies, whose permissions are the key's scopes and whose tenant
+// is the key's. Unknown, wrong and expired keys fail with
+// errInvalidAPIKey or errExpiredAPIKey; other errors come from the store.
+func (v *Verifier) VerifyAPIKey(ctx context.Context, key string) (*identity.Identity, error) {
+	prefix, ok := apiKeyPrefix(key)
+	if !ok || v.opts.APIKeys == nil {
+		return nil, errInvalidAPIKey
+	}
+	// Keys are looked up before the tenant of the request is known.
+	ctx = tenant.System(ctx)
+	k, err := v.opts.APIKeys.APIKeyByPrefix(ctx, prefix)
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return nil, errInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(k.Hash, HashAPIKey(key)) != 1 {
+		return nil, errInvalidAPIKey
+	}
+	now := time.Now()
+	if k.Expired(now) {
+		return nil, errExpiredAPIKey
+	}
+
+	if err := v.opts.APIKeys.TouchAPIKey(ctx, k.ID, now); err != nil {
+		slog.WarnContext(ctx, "Failed to record use of API key", "key_prefix", k.Prefix, "error", err)
+	}
+	return &identity.Identity{Subject: "apikey:" + k.Prefix, Permissions: k.Scopes, Tenant: k.Tenant}, nil
+}
@@ -0,0 +1,165 @@
+// Package auth authenticates API callers with JWT bearer tokens and
+// records who they are in the request context.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gowthamd/go-crud-app/internal/identity"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+)
+
+// clockSkew is the leeway allowed on exp, nbf and iat checks.
+const clockSkew = 30 * time.Second
+
+// Options selects the accepted signing keys and expected claims. HS256
+// tokens are accepted when HMACSecret is set, RS256 tokens when KeySet is.
+type Options struct {
+	HMACSecret []byte
+	KeySet     *KeySet
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// APIKeys, when set, lets machine clients authenticate with an
+	// X-API-Key header instead of a token.
+	APIKeys APIKeyStore
+}
+
+// claims are the token claims read by Verify. Roles is a custom claim
+// listing the caller's roles, and Tenant one binding the caller to a
+// tenant.
+type claims struct {
+	jwt.RegisteredClaims
+	Roles  []string `json:"roles,omitempty"`
+	Tenant string   `json:"tenant,omitempty"`
+}
+
+// Verifier validates bearer tokens.
+type Verifier struct {
+	opts   Options
+	parser *jwt.Parser
+}
+
+func NewVerifier(opts Options) (*Verifier, error) {
+	var methods []string
+	if len(opts.HMACSecret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if opts.KeySet != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("auth: no HMAC secret or key set configured")
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+
+	return &Verifier{opts: opts, parser: jwt.NewParser(parserOpts...)}, nil
+}
+
+// Verify checks token's signature and claims and returns the caller it
+// identifies.
+func (v *Verifier) Verify(ctx context.Context, token string) (*identity.Identity, error) {
+	var c claims
+	_, err := v.parser.ParseWithClaims(token, &c, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method {
+		case jwt.SigningMethodHS256:
+			return v.opts.HMACSecret, nil
+		case jwt.SigningMethodRS256:
+			kid, _ := t.Header["kid"].(string)
+			return v.opts.KeySet.Key(ctx, kid)
+		}
+		return nil, jwt.ErrTokenUnverifiable
+	})
+	if err != nil {
+		return nil, err
+	}
+	if c.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	if c.Tenant != "" && !tenant.Valid(c.Tenant) {
+		return nil, fmt.Errorf("token has invalid tenant %q", c.Tenant)
+	}
+	return &identity.Identity{Subject: c.Subject, Roles: c.Roles, Tenant: c.Tenant}, nil
+}
+
+// Middleware rejects requests without a valid bearer token or API key
+// with 401 and attaches the caller's identity to the context of the
+// others. Requests to paths in queryTokenPaths may pass the token as
+// access_token instead, for clients such as EventSource that cannot set
+// headers.
+func (v *Verifier) Middleware(queryTokenPaths ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := r.Header.Get(HeaderAPIKey); key != "" {
+				id, err := v.VerifyAPIKey(r.Context(), key)
+				switch {
+				case errors.Is(err, errInvalidAPIKey) || errors.Is(err, errExpiredAPIKey):
+					unauthorized(w, r, "", "The API key was rejected: "+err.Error())
+				case err != nil:
+					problem.Error(w, r, http.StatusInternalServerError, "Failed to verify API key")
+				default:
+					next.ServeHTTP(w, r.WithContext(identity.WithIdentity(r.Context(), id)))
+				}
+				return
+			}
+
+			token, ok := bearerToken(r)
+			if !ok {
+				for _, p := range queryTokenPaths {
+					if r.URL.Path == p {
+						token = r.URL.Query().Get("access_token")
+					}
+				}
+			}
+			if token == "" {
+				unauthorized(w, r, "", "A bearer token is required")
+				return
+			}
+
+			id, err := v.Verify(r.Context(), token)
+			if err != nil {
+				unauthorized(w, r, "invalid_token", "The bearer token is invalid: "+err.Error())
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(identity.WithIdentity(r.Context(), id)))
+		})
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// unauthorized writes a 401 problem with the RFC 6750 challenge.
+func unauthorized(w http.ResponseWriter, r *http.Request, code, detail string) {
+	challenge := `Bearer`
+	if code != "" {
+		challenge += ` error="` + code + `"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	problem.Error(w, r, http.StatusUnauthorized, detail)
+}
@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gowthamd/go-crud-app/internal/identity"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/repository"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.RegisteredClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validClaims() jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Subject:   "alice",
+		Issuer:    "https://issuer.test",
+		Audience:  jwt.ClaimStrings{"items-api"},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+}
+
+func TestVerifier_HS256(t *testing.T) {
+	v, err := NewVerifier(Options{HMACSecret: testSecret, Issuer: "https://issuer.test", Audience: "items-api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	id, err := v.Verify(ctx, sign(t, jwt.SigningMethodHS256, testSecret, "", validClaims()))
+	if err != nil || id.Subject != "alice" {
+		t.Fatalf("valid token: got %+v, %v", id, err)
+	}
+
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	noExpiry := validClaims()
+	noExpiry.ExpiresAt = nil
+	wrongIssuer := validClaims()
+	wrongIssuer.Issuer = "https://evil.test"
+	wrongAudience := validClaims()
+	wrongAudience.Audience = jwt.ClaimStrings{"other"}
+	noSubject := validClaims()
+	noSubject.Subject = ""
+
+	for name, token := range map[string]string{
+		"expired":        sign(t, jwt.SigningMethodHS256, testSecret, "", expired),
+		"no expiry":      sign(t, jwt.SigningMethodHS256, testSecret, "", noExpiry),
+		"wrong issuer":   sign(t, jwt.SigningMethodHS256, testSecret, "", wrongIssuer),
+		"wrong audience": sign(t, jwt.SigningMethodHS256, testSecret, "", wrongAudience),
+		"no subject":     sign(t, jwt.SigningMethodHS256, testSecret, "", noSubject),
+		"wrong secret":   sign(t, jwt.SigningMethodHS256, []byte("another-secret-another-secret-xx"), "", validClaims()),
+		"wrong method":   sign(t, jwt.SigningMethodHS512, testSecret, "", validClaims()),
+		"malformed":      "not.a.token",
+	} {
+		if _, err := v.Verify(ctx, token); err == nil {
+			t.Errorf("%s: expected the token to be rejected", name)
+		}
+	}
+}
+
+func TestVerifier_RS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"use": "sig",
+		"kid": "k1",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	ks, err := LoadKeySet(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewVerifier(Options{KeySet: ks})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id, err := v.Verify(ctx, sign(t, jwt.SigningMethodRS256, key, "k1", validClaims())); err != nil || id.Subject != "alice" {
+		t.Fatalf("valid token: got %+v, %v", id, err)
+	}
+	if _, err := v.Verify(ctx, sign(t, jwt.SigningMethodRS256, key, "k2", validClaims())); err == nil {
+		t.Error("expected a token with an unknown kid to be rejected")
+	}
+	// Without an HMAC secret, HS256 tokens must not be accepted.
+	if _, err := v.Verify(ctx, sign(t, jwt.SigningMethodHS256, testSecret, "", validClaims())); err == nil {
+		t.Error("expected an HS256 token to be rejected")
+	}
+}
+
+func TestKeySet_RefreshDoesNotBlockLookups(t *testing.T) {
+	jwk := func(kid string) map[string]string {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	}
+	k1, k2 := jwk("k1"), jwk("k2")
+	fetching, release := make(chan struct{}), make(chan struct{})
+	var fetches int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		keys := []map[string]string{k1}
+		if fetches > 1 {
+			close(fetching)
+			<-release
+			keys = append(keys, k2)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	ks, err := LoadKeySet(ctx, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks.fetchedAt = time.Now().Add(-jwksRefreshInterval)
+
+	rotated := make(chan error, 1)
+	go func() {
+		_, err := ks.Key(ctx, "k2")
+		rotated <- err
+	}()
+	<-fetching
+
+	found := make(chan error, 1)
+	go func() {
+		_, err := ks.Key(ctx, "k1")
+		found <- err
+	}()
+	select {
+	case err := <-found:
+		if err != nil {
+			t.Errorf("known key: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected a known key to be found while the set is being fetched")
+	}
+
+	close(release)
+	if err := <-rotated; err != nil {
+		t.Errorf("rotated key: %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	v, err := NewVerifier(Options{HMACSecret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := sign(t, jwt.SigningMethodHS256, testSecret, "", validClaims())
+	h := v.Middleware("/events")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(identity.Actor(r.Context())))
+	}))
+
+	tests := []struct {
+		name      string
+		target    string
+		header    string
+		want      int
+		challenge string
+	}{
+		{"missing", "/items", "", http.StatusUnauthorized, `Bearer`},
+		{"invalid", "/items", "Bearer nope", http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{"wrong scheme", "/items", "Basic " + token, http.StatusUnauthorized, `Bearer`},
+		{"valid", "/items", "Bearer " + token, http.StatusOK, ""},
+		{"query token", "/events?access_token=" + token, "", http.StatusOK, ""},
+		{"query token elsewhere", "/items?access_token=" + token, "", http.StatusUnauthorized, `Bearer`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, rec.Code)
+		}
+		if got := rec.Header().Get("WWW-Authenticate"); got != tt.challenge {
+			t.Errorf("%s: expected challenge %q, got %q", tt.name, tt.challenge, got)
+		}
+		if tt.want == http.StatusOK && rec.Body.String() != "alice" {
+			t.Errorf("%s: expected the handler to see alice, got %q", tt.name, rec.Body.String())
+		}
+	}
+}
+
+func TestVerifier_APIKey(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryItemRepository()
+	v, err := NewVerifier(Options{HMACSecret: testSecret, APIKeys: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, prefix := NewAPIKey()
+	if p, ok := apiKeyPrefix(key); !ok || p != prefix {
+		t.Fatalf("expected %s to have prefix %s, got %s", key, prefix, p)
+	}
+	store.CreateAPIKey(ctx, &models.APIKey{Name: "sync", Prefix: prefix, Hash: HashAPIKey(key), Scopes: []string{PermItemsRead}})
+
+	id, err := v.VerifyAPIKey(ctx, key)
+	if err != nil || id.Subject != "apikey:"+prefix || len(id.Permissions) != 1 {
+		t.Fatalf("valid key: got %+v, %v", id, err)
+	}
+	other, _ := NewAPIKey()
+	for _, k := range []string{key + "x", prefix + "_wrong", other, "garbage", ""} {
+		if _, err := v.VerifyAPIKey(ctx, k); !errors.Is(err, errInvalidAPIKey) {
+			t.Errorf("%q: expected errInvalidAPIKey, got %v", k, err)
+		}
+	}
+
+	expired, expiredPrefix := NewAPIKey()
+	past := time.Now().Add(-time.Minute)
+	store.CreateAPIKey(ctx, &models.APIKey{Name: "old", Prefix: expiredPrefix, Hash: HashAPIKey(expired), Scopes: []string{PermItemsRead}, ExpiresAt: &past})
+	if _, err := v.VerifyAPIKey(ctx, expired); !errors.Is(err, errExpiredAPIKey) {
+		t.Errorf("expected errExpiredAPIKey, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// jwksRefreshInterval limits how often a remote key set is fetched again
+// when a token names a key it does not contain.
+const jwksRefreshInterval = time.Minute
+
+// jwksTimeout bounds a single fetch of a remote key set.
+const jwksTimeout = 10 * time.Second
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// KeySet holds the RSA public keys of a JSON Web Key Set, loaded from a
+// file or an http(s) URL. Remote sets are refreshed when a token is signed
+// with a key id they do not know yet, so key rotation needs no restart.
+type KeySet struct {
+	source string
+	client *http.Client
+	// loads lets concurrent refreshes share one fetch.
+	loads singleflight.Group
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// LoadKeySet reads the key set at source, failing if it cannot be loaded.
+func LoadKeySet(ctx context.Context, source string) (*KeySet, error) {
+	ks := &KeySet{source: source, client: &http.Client{Timeout: jwksTimeout}}
+	if err := ks.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) remote() bool {
+	return strings.HasPrefix(ks.source, "http://") || strings.HasPrefix(ks.source, "https://")
+}
+
+// Key returns the key with id kid. An empty kid matches the only key of a
+// single-key set.
+func (ks *KeySet) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	ks.mu.Lock()
+	key := ks.lookup(kid)
+	due := ks.remote() && time.Since(ks.fetchedAt) >= jwksRefreshInterval
+	ks.mu.Unlock()
+
+	if key != nil {
+		return key, nil
+	}
+	if !due {
+		return nil, errUnknownKey
+	}
+	if err := ks.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if key := ks.lookup(kid); key != nil {
+		return key, nil
+	}
+	return nil, errUnknownKey
+}
+
+// lookup finds a key by id. Callers must hold ks.mu.
+func (ks *KeySet) lookup(kid string) *rsa.PublicKey {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key
+		}
+	}
+	return ks.keys[kid]
+}
+
+// refresh reloads the key set, or waits for the reload already under way.
+// The set is loaded without holding ks.mu, so lookups of known keys are
+// not held up by a slow key server.
+func (ks *KeySet) refresh(ctx context.Context) error {
+	_, err, _ := ks.loads.Do(ks.source, func() (any, error) {
+		ks.mu.Lock()
+		ks.fetchedAt = time.Now()
+		ks.mu.Unlock()
+
+		keys, err := ks.load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ks.mu.Lock()
+		ks.keys = keys
+		ks.mu.Unlock()
+		return nil, nil
+	})
+	return err
+}
+
+func (ks *KeySet) load(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	var data []byte
+	var err error
+	if ks.remote() {
+		data, err = ks.fetch(ctx)
+	} else {
+		data, err = os.ReadFile(ks.source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWKS: %w", err)
+	}
+
+	keys, err := parseKeySet(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+	return keys, nil
+}
+
+func (ks *KeySet) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// parseKeySet extracts the RSA signing keys of a JWKS document. Keys of
+// other types or for encryption are skipped.
+func parseKeySet(data []byte) (map[string]*rsa.PublicKey, error) {
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range doc.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: invalid modulus", k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("key %q: invalid exponent", k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no RSA signing keys")
+	}
+	return keys, nil
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/gowthamd/go-crud-app/internal/identity"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+)
+
+// Permissions guarding the API routes.
+const (
+	// PermItemsRead covers listing, reading, searching, exporting and
+	// following items.
+	PermItemsRead = "items:read"
+	// PermItemsWrite covers creating, updating, restoring and reverting
+	// single items.
+	PermItemsWrite  = "items:write"
+	PermItemsDelete = "items:delete"
+	// PermItemsBulk covers bulk operations and imports.
+	PermItemsBulk = "items:bulk"
+	// PermWebhooksManage covers webhook subscriptions and their deliveries.
+	PermWebhooksManage = "webhooks:manage"
+	// PermKeysManage covers creating, listing and revoking API keys.
+	PermKeysManage = "keys:manage"
+	// PermLogsManage covers reading and changing the log level, which
+	// applies to the whole process rather than one tenant. No default
+	// role holds it.
+	PermLogsManage = "logs:manage"
+	// PermTenantsCross lets callers without a tenant claim act in the
+	// tenant named by X-Tenant-ID or the host. No default role holds it.
+	PermTenantsCross = "tenants:cross"
+)
+
+// Permissions lists every permission a policy may grant.
+var Permissions = []string{PermItemsRead, PermItemsWrite, PermItemsDelete, PermItemsBulk, PermWebhooksManage, PermKeysManage, PermLogsManage, PermTenantsCross}
+
+// Policy maps role names to the permissions they grant. A caller holds
+// the union of the permissions of its roles; unknown roles grant nothing.
+type Policy map[string][]string
+
+// DefaultPolicy is used when no policy file is configured: viewers read,
+// editors also create and update, admins may do everything within their
+// tenant. Permissions reaching beyond one tenant are left to roles of a
+// policy file.
+func DefaultPolicy() Policy {
+	return Policy{
+		"viewer": {PermItemsRead},
+		"editor": {PermItemsRead, PermItemsWrite},
+		"admin": slices.DeleteFunc(slices.Clone(Permissions), func(p string) bool {
+			return p == PermTenantsCross || p == PermLogsManage
+		}),
+	}
+}
+
+// LoadPolicy reads a policy from a JSON file mapping role names to
+// permission lists, e.g. {"viewer": ["items:read"]}.
+func LoadPolicy(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate rejects empty policies and unknown permissions, which are
+// most likely typos.
+func (p Policy) Validate() error {
+	if len(p) == 0 {
+		return fmt.Errorf("no roles defined")
+	}
+	for role, perms := range p {
+		for _, perm := range perms {
+			if !slices.Contains(Permissions, perm) {
+				return fmt.Errorf("role %q: unknown permission %q", role, perm)
+			}
+		}
+	}
+	return nil
+}
+
+// Allows reports whether id holds perm directly or through any of its
+// roles.
+func (p Policy) Allows(id *identity.Identity, perm string) bool {
+	if slices.Contains(id.Permissions, perm) {
+		return true
+	}
+	for _, role := range id.Roles {
+		if slices.Contains(p[role], perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// CrossTenant reports whether id holds PermTenantsCross, for
+// tenant.Resolver.
+func (p Policy) CrossTenant(id *identity.Identity) bool {
+	return p.Allows(id, PermTenantsCross)
+}
+
+// Require rejects callers lacking perm with 403. It must run after the
+// authentication middleware.
+func (p Policy) Require(perm string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := identity.FromContext(r.Context())
+			if !ok || !p.Allows(id, perm) {
+				problem.Error(w, r, http.StatusForbidden, fmt.Sprintf("Missing permission %q", perm))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gowthamd/go-crud-app/internal/identity"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	p := DefaultPolicy()
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		roles []string
+		perm  string
+		want  bool
+	}{
+		{[]string{"viewer"}, PermItemsRead, true},
+		{[]string{"viewer"}, PermItemsWrite, false},
+		{[]string{"editor"}, PermItemsWrite, true},
+		{[]string{"editor"}, PermItemsDelete, false},
+		{[]string{"viewer", "editor"}, PermItemsWrite, true},
+		{[]string{"admin"}, PermItemsBulk, true},
+		{[]string{"admin"}, PermWebhooksManage, true},
+		{[]string{"admin"}, PermLogsManage, false},
+		{[]string{"admin"}, PermTenantsCross, false},
+		{[]string{"unknown"}, PermItemsRead, false},
+		{nil, PermItemsRead, false},
+	}
+	for _, tt := range tests {
+		if got := p.Allows(&identity.Identity{Subject: "s", Roles: tt.roles}, tt.perm); got != tt.want {
+			t.Errorf("%v %s: expected %v, got %v", tt.roles, tt.perm, tt.want, got)
+		}
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	p, err := LoadPolicy(write("ok.json", `{"auditor": ["items:read"], "janitor": ["items:read", "items:delete"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Allows(&identity.Identity{Roles: []string{"janitor"}}, PermItemsDelete) {
+		t.Error("expected janitors to delete")
+	}
+	if p.Allows(&identity.Identity{Roles: []string{"admin"}}, PermItemsRead) {
+		t.Error("expected a loaded policy to replace the default roles")
+	}
+
+	if _, err := LoadPolicy(write("typo.json", `{"viewer": ["items:raed"]}`)); err == nil || !strings.Contains(err.Error(), "items:raed") {
+		t.Errorf("expected an unknown permission error, got %v", err)
+	}
+	if _, err := LoadPolicy(write("empty.json", `{}`)); err == nil {
+		t.Error("expected an empty policy to be rejected")
+	}
+	if _, err := LoadPolicy(write("bad.json", `[`)); err == nil {
+		t.Error("expected malformed JSON to be rejected")
+	}
+}
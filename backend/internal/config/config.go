@@ -1,43 +1,240 @@
+// Package config loads the service configuration from defaults, a YAML
+// file, environment variables and command-line flags.
 package config
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
 	"os"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/gowthamd/go-crud-app/internal/ratelimit"
+	"github.com/gowthamd/go-crud-app/internal/tracing"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Port           string
-	DBUrl          string
-	LogLevel       string
+	Port string
+	// ReadHeaderTimeout and IdleTimeout bound slow and idle connections;
+	// RequestTimeout cancels API requests other than the change feed and
+	// exports, and ShutdownTimeout is how long in-flight requests get on
+	// shutdown.
+	ReadHeaderTimeout time.Duration
+	IdleTimeout       time.Duration
+	RequestTimeout    time.Duration
+	ShutdownTimeout   time.Duration
+	// MetricsPort, when set, serves /metrics on a separate admin port
+	// instead of the API port.
+	MetricsPort string
+
+	// Store is the item store backend: "postgres" or "memory".
+	Store string
+	DBUrl string
+	// DBMaxConns, DBMinConns, DBMaxConnLifetime and DBMaxConnIdleTime size
+	// the connection pool; DBConnectTimeout bounds the startup ping.
+	DBMaxConns        int
+	DBMinConns        int
+	DBMaxConnLifetime time.Duration
+	DBMaxConnIdleTime time.Duration
+	DBConnectTimeout  time.Duration
+	// MigrateOnStart applies pending migrations before serving.
+	MigrateOnStart bool
+
 	AllowedOrigins []string
+	// CORSMaxAge is how long browsers may cache preflight responses.
+	CORSMaxAge time.Duration
+
+	// LogLevel is the initial minimum level logged; it can be changed at
+	// runtime through the admin API.
+	LogLevel slog.Level
+	// TracingExporter sends spans to "otlp", "stdout", "file" (written to
+	// TracingFile) or nowhere ("none"); TracingSampleRatio is the share
+	// of new traces recorded.
+	TracingExporter    string
+	TracingFile        string
+	TracingSampleRatio float64
+
+	// PriceFormat is how prices are encoded in JSON: "number" or "string".
+	PriceFormat string
+	// DefaultCurrency is the ISO 4217 code given to items created without one.
+	DefaultCurrency string
+	// TrashRetention is how long deleted items stay restorable before the
+	// purge job removes them; zero disables purging.
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+	// WebhookPollInterval is how often queued webhook deliveries are
+	// picked up; WebhookMaxAttempts is how often one is tried before it
+	// is marked failed.
+	WebhookPollInterval time.Duration
+	WebhookMaxAttempts  int
+	// WebhookAllowPrivate lets webhooks target loopback and private
+	// addresses, for local development.
+	WebhookAllowPrivate bool
+	// JWT authentication is enabled when JWTSecret (HS256) or JWKSSource
+	// (RS256; a file path or http(s) URL) is set. JWTIssuer and
+	// JWTAudience, when set, must match the token claims.
+	JWTSecret   string
+	JWKSSource  string
+	JWTIssuer   string
+	JWTAudience string
+	// RBACPolicyFile optionally replaces the built-in role policy with a
+	// JSON file mapping roles to permissions.
+	RBACPolicyFile string
+	// TenantBaseDomain, when set, resolves tenants from subdomains of it.
+	// TenantMaxItems is the default per-tenant item quota and TenantQuotas
+	// overrides it for named tenants; zero means no limit.
+	TenantBaseDomain string
+	TenantMaxItems   int
+	TenantQuotas     map[string]int
+	// RateLimitRead and RateLimitWrite limit each client's safe and
+	// unsafe requests; RateLimitStore keeps the buckets in "memory" (per
+	// replica) or "postgres" (shared). TrustedProxies are the proxies
+	// whose X-Forwarded-For is believed.
+	RateLimitRead  ratelimit.Limit
+	RateLimitWrite ratelimit.Limit
+	// RateLimitIP limits all requests per client IP, checked before
+	// authentication.
+	RateLimitIP    ratelimit.Limit
+	RateLimitStore string
+	TrustedProxies []netip.Prefix
+
+	// sources records where each setting came from, for Print.
+	sources map[string]string
+}
+
+// AuthEnabled reports whether API requests must carry a bearer token.
+func (c *Config) AuthEnabled() bool {
+	return c.JWTSecret != "" || c.JWKSSource != ""
+}
+
+// Problems lists every invalid setting found by LoadConfig.
+type Problems []string
+
+func (p Problems) Error() string {
+	return "invalid configuration: " + strings.Join(p, "; ")
+}
+
+// Default returns the configuration used when no source sets anything.
+func Default() *Config {
+	c := &Config{sources: make(map[string]string)}
+	for _, s := range settings {
+		if err := s.set(c, s.def); err != nil {
+			panic(fmt.Sprintf("config: default of %s: %v", s.key, err))
+		}
+		c.sources[s.key] = "default"
+	}
+	return c
 }
 
-func LoadConfig() (*Config, error) {
+// LoadConfig builds the configuration from, in increasing precedence,
+// the defaults, the YAML file named by -config or CONFIG_FILE, the
+// environment (including a .env file) and the flags in args. It returns
+// the arguments left after the flags. Every invalid setting is reported
+// at once as Problems, along with a Config holding the valid ones.
+func LoadConfig(args []string) (*Config, []string, error) {
 	_ = godotenv.Load()
 
-	origins := getEnv("ALLOWED_ORIGINS", "http://localhost:8080")
-	var parsedOrigins []string
-	for _, o := range strings.Split(origins, ",") {
-		if trimmed := strings.TrimSpace(o); trimmed != "" {
-			parsedOrigins = append(parsedOrigins, trimmed)
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML configuration file (env CONFIG_FILE)")
+	flags := make(map[string]string)
+	for _, s := range settings {
+		usage := fmt.Sprintf("%s (env %s, default %q)", s.usage, s.env, s.def)
+		if s.isBool {
+			fs.BoolFunc(s.key, usage, func(v string) error { flags[s.key] = v; return nil })
+		} else {
+			fs.Func(s.key, usage, func(v string) error { flags[s.key] = v; return nil })
 		}
 	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
 
-	cfg := &Config{
-		Port:           getEnv("PORT", "8000"),
-		DBUrl:          getEnv("DB_URL", ""),
-		LogLevel:       getEnv("LOG_LEVEL", "info"),
-		AllowedOrigins: parsedOrigins,
+	var problems Problems
+	file := make(map[string]string)
+	if *configFile != "" {
+		var err error
+		if file, err = readFile(*configFile); err != nil {
+			problems = append(problems, err.Error())
+		}
 	}
 
-	return cfg, nil
+	c := &Config{sources: make(map[string]string)}
+	for _, s := range settings {
+		value, source := s.def, "default"
+		if v, ok := file[s.key]; ok {
+			value, source = v, "file "+*configFile
+			delete(file, s.key)
+		}
+		if v, ok := os.LookupEnv(s.env); ok {
+			value, source = v, "env "+s.env
+		}
+		if v, ok := flags[s.key]; ok {
+			value, source = v, "flag -"+s.key
+		}
+		c.sources[s.key] = source
+		if err := s.set(c, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s (%s): %v", s.key, source, err))
+			// Leave the default in place so later checks see a sane value.
+			s.set(c, s.def)
+		}
+	}
+	for key := range file {
+		problems = append(problems, fmt.Sprintf("%s: unknown setting in %s", key, *configFile))
+	}
+	problems = append(problems, c.validate()...)
+
+	if len(problems) > 0 {
+		return c, fs.Args(), problems
+	}
+	return c, fs.Args(), nil
 }
 
-func getEnv(key, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// validate checks the settings that depend on each other.
+func (c *Config) validate() Problems {
+	var problems Problems
+	if c.Store == "postgres" && c.DBUrl == "" {
+		problems = append(problems, "database.url (DB_URL): required by the postgres store")
+	}
+	if c.DBMinConns > c.DBMaxConns {
+		problems = append(problems, "database.min_conns (DB_MIN_CONNS): must not exceed database.max_conns")
+	}
+	if c.MigrateOnStart && c.Store != "postgres" {
+		problems = append(problems, "migrate (MIGRATE_ON_START): requires the postgres store")
+	}
+	if c.RateLimitStore == "postgres" && c.Store != "postgres" {
+		problems = append(problems, "rate_limit.store (RATE_LIMIT_STORE): postgres requires the postgres store")
 	}
-	return fallback
+	if c.MetricsPort != "" && c.MetricsPort == c.Port {
+		problems = append(problems, "server.metrics_port (METRICS_PORT): must differ from server.port")
+	}
+	if c.TracingExporter == tracing.ExporterFile && c.TracingFile == "" {
+		problems = append(problems, "tracing.file (TRACING_FILE): required by the file exporter")
+	}
+	return problems
+}
+
+// Print writes the effective configuration as YAML, commenting where
+// each value came from. Secrets are masked.
+func (c *Config) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, s := range settings {
+		value := s.get(c)
+		if s.mask != nil && value != "" {
+			value = s.mask(value)
+		}
+		fmt.Fprintf(tw, "%s: %s\t# %s\n", s.key, strconv.Quote(value), c.sources[s.key])
+	}
+	return tw.Flush()
+}
+
+// IsHelp reports whether LoadConfig failed because help was requested.
+func IsHelp(err error) bool {
+	return errors.Is(err, flag.ErrHelp)
 }
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	path := writeFile(t, `
+server:
+  port: 8001
+  request_timeout: 30s
+database.max_conns: 20
+tenants:
+  quotas: {acme: 5, beta: 7}
+cors:
+  allowed_origins: [https://a.example, https://b.example]
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("DB_URL", "postgres://app:secret@db/app")
+	t.Setenv("PORT", "8002")
+
+	cfg, args, err := LoadConfig([]string{"-server.port", "8003", "config", "print"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(args, " ") != "config print" {
+		t.Errorf("args = %q", args)
+	}
+	if cfg.Port != "8003" {
+		t.Errorf("Port = %q, want the flag", cfg.Port)
+	}
+	if cfg.RequestTimeout != 30*time.Second || cfg.DBMaxConns != 20 {
+		t.Errorf("RequestTimeout = %v, DBMaxConns = %d, want the file values", cfg.RequestTimeout, cfg.DBMaxConns)
+	}
+	if cfg.TenantQuotas["acme"] != 5 || cfg.TenantQuotas["beta"] != 7 {
+		t.Errorf("TenantQuotas = %v", cfg.TenantQuotas)
+	}
+	if len(cfg.AllowedOrigins) != 2 || cfg.AllowedOrigins[1] != "https://b.example" {
+		t.Errorf("AllowedOrigins = %q", cfg.AllowedOrigins)
+	}
+	if cfg.IdleTimeout != Default().IdleTimeout {
+		t.Errorf("IdleTimeout = %v, want the default", cfg.IdleTimeout)
+	}
+
+	for key, want := range map[string]string{
+		"server.port":             "flag -server.port",
+		"server.request_timeout":  "file " + path,
+		"database.url":            "env DB_URL",
+		"server.shutdown_timeout": "default",
+	} {
+		if got := cfg.sources[key]; got != want {
+			t.Errorf("source of %s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLoadConfig_Problems(t *testing.T) {
+	path := writeFile(t, "server:\n  prot: 8080\n")
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("STORE", "postgres")
+	t.Setenv("DB_URL", "")
+	t.Setenv("PORT", "http")
+	t.Setenv("DB_MIN_CONNS", "99")
+	t.Setenv("TRACING_SAMPLE_RATIO", "2")
+	t.Setenv("JWT_HS256_SECRET", "too-short")
+
+	cfg, _, err := LoadConfig(nil)
+	var problems Problems
+	if !errors.As(err, &problems) {
+		t.Fatalf("err = %v, want Problems", err)
+	}
+	for _, want := range []string{
+		"server.port (env PORT)",
+		"tracing.sample_ratio (env TRACING_SAMPLE_RATIO)",
+		"auth.jwt_secret (env JWT_HS256_SECRET)",
+		"server.prot: unknown setting",
+		"database.url (DB_URL): required",
+		"database.min_conns (DB_MIN_CONNS)",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("problems %q lack %q", problems, want)
+		}
+	}
+	if strings.Contains(err.Error(), "too-short") {
+		t.Errorf("problems %q echo a secret", problems)
+	}
+	if cfg == nil || cfg.Port != Default().Port {
+		t.Errorf("invalid settings should keep their defaults")
+	}
+}
+
+func TestLoadConfig_BoolFlag(t *testing.T) {
+	t.Setenv("DB_URL", "postgres://db/app")
+	cfg, _, err := LoadConfig([]string{"-migrate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.MigrateOnStart {
+		t.Error("-migrate should enable MigrateOnStart")
+	}
+}
+
+func TestPrint(t *testing.T) {
+	cfg := Default()
+	cfg.DBUrl = "postgres://app:hunter2@db/app?sslmode=disable"
+	cfg.JWTSecret = strings.Repeat("s", 32)
+	cfg.sources["database.url"] = "env DB_URL"
+
+	var b strings.Builder
+	if err := cfg.Print(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if strings.Contains(out, "hunter2") || strings.Contains(out, cfg.JWTSecret) {
+		t.Errorf("output leaks a secret:\n%s", out)
+	}
+	for _, want := range []string{
+		`database.url: "postgres://app:xxxxx@db/app?sslmode=disable"`,
+		`auth.jwt_secret: "********"`,
+		`# env DB_URL`,
+		`trash.retention: "720h"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMaskDSN(t *testing.T) {
+	tests := map[string]string{
+		"postgres://app:pw@db:5432/app":            "postgres://app:xxxxx@db:5432/app",
+		"host=db user=app password=pw dbname=app":  "host=db user=app password=xxxxx dbname=app",
+		"postgres://db/app?password=pw&sslmode=on": "postgres://db/app?password=xxxxx&sslmode=on",
+	}
+	for in, want := range tests {
+		if got := maskDSN(in); got != want {
+			t.Errorf("maskDSN(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		720 * time.Hour:        "720h",
+		time.Minute:            "1m",
+		90 * time.Second:       "1m30s",
+		500 * time.Millisecond: "500ms",
+		0:                      "0s",
+	}
+	for d, want := range tests {
+		if got := formatDuration(d); got != want {
+			t.Errorf("formatDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
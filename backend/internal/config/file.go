@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// readFile reads a YAML configuration file into settings keyed like the
+// flags. Sections may be nested or written as dotted keys:
+//
+//	server:
+//	  port: 8080
+//	database.max_conns: 50
+//	tenants:
+//	  quotas: {acme: 1000}
+//
+// Lists and tenant=limit maps are joined with commas, as in the
+// environment.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	values := make(map[string]string)
+	for k, v := range doc {
+		flatten(k, v, values)
+	}
+	return values, nil
+}
+
+// flatten stores v under key, or each of its entries under key.entry if
+// v is a section rather than a setting.
+func flatten(key string, v any, values map[string]string) {
+	if m, ok := v.(map[string]any); ok && !isSetting(key) {
+		for k, child := range m {
+			flatten(key+"."+k, child, values)
+		}
+		return
+	}
+
+	switch v := v.(type) {
+	case nil:
+		values[key] = ""
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		values[key] = strings.Join(items, ",")
+	case map[string]any:
+		pairs := make([]string, 0, len(v))
+		for k, item := range v {
+			pairs = append(pairs, fmt.Sprintf("%s=%v", k, item))
+		}
+		slices.Sort(pairs)
+		values[key] = strings.Join(pairs, ",")
+	default:
+		values[key] = fmt.Sprint(v)
+	}
+}
+
+func isSetting(key string) bool {
+	return slices.ContainsFunc(settings, func(s setting) bool { return s.key == key })
+}
@@ -0,0 +1,348 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/internal/logging"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/ratelimit"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+	"github.com/gowthamd/go-crud-app/internal/tracing"
+)
+
+// setting is one configuration value. key names it in the YAML file and
+// as a flag, env in the environment.
+type setting struct {
+	key, env, def, usage string
+	binding
+	// mask hides secrets in Print.
+	mask func(string) string
+}
+
+// binding parses a value into its Config field and formats it back.
+type binding struct {
+	set    func(c *Config, v string) error
+	get    func(c *Config) string
+	isBool bool
+}
+
+var settings = []setting{
+	{key: "server.port", env: "PORT", def: "8000", usage: "port the API listens on", binding: portVar(func(c *Config) *string { return &c.Port }, false)},
+	{key: "server.read_header_timeout", env: "SERVER_READ_HEADER_TIMEOUT", def: "10s", usage: "time allowed to read request headers", binding: durationVar(func(c *Config) *time.Duration { return &c.ReadHeaderTimeout }, true)},
+	{key: "server.idle_timeout", env: "SERVER_IDLE_TIMEOUT", def: "2m", usage: "time idle keep-alive connections are kept", binding: durationVar(func(c *Config) *time.Duration { return &c.IdleTimeout }, true)},
+	{key: "server.request_timeout", env: "SERVER_REQUEST_TIMEOUT", def: "60s", usage: "time allowed to serve an API request", binding: durationVar(func(c *Config) *time.Duration { return &c.RequestTimeout }, true)},
+	{key: "server.shutdown_timeout", env: "SERVER_SHUTDOWN_TIMEOUT", def: "5s", usage: "time in-flight requests get on shutdown", binding: durationVar(func(c *Config) *time.Duration { return &c.ShutdownTimeout }, true)},
+	{key: "server.metrics_port", env: "METRICS_PORT", usage: "serve /metrics on this port instead of the API port", binding: portVar(func(c *Config) *string { return &c.MetricsPort }, true)},
+
+	{key: "store", env: "STORE", def: "postgres", usage: "item store backend: postgres or memory", binding: enumVar(func(c *Config) *string { return &c.Store }, "postgres", "memory")},
+	{key: "database.url", env: "DB_URL", usage: "PostgreSQL connection string", binding: stringVar(func(c *Config) *string { return &c.DBUrl }), mask: maskDSN},
+	{key: "database.max_conns", env: "DB_MAX_CONNS", def: "25", usage: "maximum size of the connection pool", binding: intVar(func(c *Config) *int { return &c.DBMaxConns }, 1)},
+	{key: "database.min_conns", env: "DB_MIN_CONNS", def: "2", usage: "connections kept open when idle", binding: intVar(func(c *Config) *int { return &c.DBMinConns }, 0)},
+	{key: "database.max_conn_lifetime", env: "DB_MAX_CONN_LIFETIME", def: "1h", usage: "age at which connections are replaced", binding: durationVar(func(c *Config) *time.Duration { return &c.DBMaxConnLifetime }, true)},
+	{key: "database.max_conn_idle_time", env: "DB_MAX_CONN_IDLE_TIME", def: "30m", usage: "idle time after which connections are closed", binding: durationVar(func(c *Config) *time.Duration { return &c.DBMaxConnIdleTime }, true)},
+	{key: "database.connect_timeout", env: "DB_CONNECT_TIMEOUT", def: "5s", usage: "time allowed to reach the database at startup", binding: durationVar(func(c *Config) *time.Duration { return &c.DBConnectTimeout }, true)},
+	{key: "migrate", env: "MIGRATE_ON_START", def: "false", usage: "apply pending migrations before serving", binding: boolVar(func(c *Config) *bool { return &c.MigrateOnStart })},
+
+	{key: "cors.allowed_origins", env: "ALLOWED_ORIGINS", def: "http://localhost:8080", usage: "comma-separated origins allowed by CORS", binding: listVar(func(c *Config) *[]string { return &c.AllowedOrigins })},
+	{key: "cors.max_age", env: "CORS_MAX_AGE", def: "5m", usage: "time browsers may cache preflight responses", binding: durationVar(func(c *Config) *time.Duration { return &c.CORSMaxAge }, false)},
+
+	{key: "log.level", env: "LOG_LEVEL", def: "info", usage: "minimum level logged: debug, info, warn or error", binding: levelVar()},
+	{key: "tracing.exporter", env: "TRACING_EXPORTER", def: tracing.ExporterNone, usage: "where spans go: none, otlp, stdout or file", binding: enumVar(func(c *Config) *string { return &c.TracingExporter }, tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout, tracing.ExporterFile)},
+	{key: "tracing.file", env: "TRACING_FILE", def: "traces.jsonl", usage: "file the file exporter appends spans to", binding: stringVar(func(c *Config) *string { return &c.TracingFile })},
+	{key: "tracing.sample_ratio", env: "TRACING_SAMPLE_RATIO", def: "1", usage: "share of new traces recorded, 0 to 1", binding: ratioVar(func(c *Config) *float64 { return &c.TracingSampleRatio })},
+
+	{key: "items.price_format", env: "PRICE_JSON_FORMAT", def: "number", usage: "JSON encoding of prices: number or string", binding: enumVar(func(c *Config) *string { return &c.PriceFormat }, "number", "string")},
+	{key: "items.default_currency", env: "DEFAULT_CURRENCY", def: "USD", usage: "ISO 4217 currency of items created without one", binding: currencyVar(func(c *Config) *string { return &c.DefaultCurrency })},
+	{key: "trash.retention", env: "TRASH_RETENTION", def: "720h", usage: "time trashed items stay restorable; 0 keeps them", binding: durationVar(func(c *Config) *time.Duration { return &c.TrashRetention }, false)},
+	{key: "trash.purge_interval", env: "TRASH_PURGE_INTERVAL", def: "1h", usage: "how often expired items are purged", binding: durationVar(func(c *Config) *time.Duration { return &c.TrashPurgeInterval }, true)},
+	{key: "webhooks.poll_interval", env: "WEBHOOK_POLL_INTERVAL", def: "5s", usage: "how often queued deliveries are picked up", binding: durationVar(func(c *Config) *time.Duration { return &c.WebhookPollInterval }, true)},
+	{key: "webhooks.allow_private", env: "WEBHOOK_ALLOW_PRIVATE", def: "false", usage: "let webhooks reach loopback, private and link-local addresses", binding: boolVar(func(c *Config) *bool { return &c.WebhookAllowPrivate })},
+	{key: "webhooks.max_attempts", env: "WEBHOOK_MAX_ATTEMPTS", def: "8", usage: "attempts before a delivery is marked failed", binding: intVar(func(c *Config) *int { return &c.WebhookMaxAttempts }, 1)},
+
+	{key: "auth.jwt_secret", env: "JWT_HS256_SECRET", usage: "HS256 signing secret of at least 32 bytes", binding: secretVar(func(c *Config) *string { return &c.JWTSecret }), mask: maskAll},
+	{key: "auth.jwks", env: "JWT_JWKS", usage: "RS256 key set, as a file path or http(s) URL", binding: stringVar(func(c *Config) *string { return &c.JWKSSource })},
+	{key: "auth.issuer", env: "JWT_ISSUER", usage: "required iss claim", binding: stringVar(func(c *Config) *string { return &c.JWTIssuer })},
+	{key: "auth.audience", env: "JWT_AUDIENCE", usage: "required aud claim", binding: stringVar(func(c *Config) *string { return &c.JWTAudience })},
+	{key: "auth.policy_file", env: "RBAC_POLICY_FILE", usage: "JSON file mapping roles to permissions", binding: stringVar(func(c *Config) *string { return &c.RBACPolicyFile })},
+
+	{key: "tenants.base_domain", env: "TENANT_BASE_DOMAIN", usage: "resolve tenants from subdomains of this domain", binding: stringVar(func(c *Config) *string { return &c.TenantBaseDomain })},
+	{key: "tenants.max_items", env: "TENANT_MAX_ITEMS", def: "0", usage: "live items allowed per tenant; 0 is unlimited", binding: intVar(func(c *Config) *int { return &c.TenantMaxItems }, 0)},
+	{key: "tenants.quotas", env: "TENANT_QUOTAS", usage: "per-tenant quotas, e.g. acme=1000,beta=50", binding: quotasVar()},
+
+	{key: "rate_limit.read", env: "RATE_LIMIT_READ", def: "600/1m", usage: "read requests per client, as requests/duration or off", binding: limitVar(func(c *Config) *ratelimit.Limit { return &c.RateLimitRead })},
+	{key: "rate_limit.write", env: "RATE_LIMIT_WRITE", def: "120/1m", usage: "write requests per client, as requests/duration or off", binding: limitVar(func(c *Config) *ratelimit.Limit { return &c.RateLimitWrite })},
+	{key: "rate_limit.ip", env: "RATE_LIMIT_IP", def: "1200/1m", usage: "requests per client IP before authentication, as requests/duration or off", binding: limitVar(func(c *Config) *ratelimit.Limit { return &c.RateLimitIP })},
+	{key: "rate_limit.store", env: "RATE_LIMIT_STORE", def: "memory", usage: "where buckets live: memory or postgres", binding: enumVar(func(c *Config) *string { return &c.RateLimitStore }, "memory", "postgres")},
+	{key: "rate_limit.trusted_proxies", env: "TRUSTED_PROXIES", usage: "comma-separated proxies whose X-Forwarded-For is trusted", binding: proxiesVar()},
+}
+
+func stringVar(field func(*Config) *string) binding {
+	return binding{
+		set: func(c *Config, v string) error { *field(c) = strings.TrimSpace(v); return nil },
+		get: func(c *Config) string { return *field(c) },
+	}
+}
+
+func secretVar(field func(*Config) *string) binding {
+	b := stringVar(field)
+	b.set = func(c *Config, v string) error {
+		if v != "" && len(v) < 32 {
+			return errors.New("must be at least 32 bytes")
+		}
+		*field(c) = v
+		return nil
+	}
+	return b
+}
+
+func portVar(field func(*Config) *string, optional bool) binding {
+	b := stringVar(field)
+	b.set = func(c *Config, v string) error {
+		v = strings.TrimSpace(v)
+		if v == "" && optional {
+			*field(c) = v
+			return nil
+		}
+		if n, err := strconv.Atoi(v); err != nil || n < 1 || n > 65535 {
+			return errors.New("must be a port number")
+		}
+		*field(c) = v
+		return nil
+	}
+	return b
+}
+
+func enumVar(field func(*Config) *string, values ...string) binding {
+	b := stringVar(field)
+	b.set = func(c *Config, v string) error {
+		v = strings.TrimSpace(v)
+		if !slices.Contains(values, v) {
+			return fmt.Errorf("want %s", strings.Join(values, " or "))
+		}
+		*field(c) = v
+		return nil
+	}
+	return b
+}
+
+func currencyVar(field func(*Config) *string) binding {
+	b := stringVar(field)
+	b.set = func(c *Config, v string) error {
+		v = models.NormalizeCurrency(v)
+		if !models.IsCurrency(v) {
+			return errors.New("must be an ISO 4217 code")
+		}
+		*field(c) = v
+		return nil
+	}
+	return b
+}
+
+func intVar(field func(*Config) *int, min int) binding {
+	return binding{
+		set: func(c *Config, v string) error {
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil || n < min {
+				return fmt.Errorf("must be an integer of at least %d", min)
+			}
+			*field(c) = n
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Itoa(*field(c)) },
+	}
+}
+
+func boolVar(field func(*Config) *bool) binding {
+	return binding{
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return errors.New("must be true or false")
+			}
+			*field(c) = b
+			return nil
+		},
+		get:    func(c *Config) string { return strconv.FormatBool(*field(c)) },
+		isBool: true,
+	}
+}
+
+func ratioVar(field func(*Config) *float64) binding {
+	return binding{
+		set: func(c *Config, v string) error {
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || f < 0 || f > 1 {
+				return errors.New("must be between 0 and 1")
+			}
+			*field(c) = f
+			return nil
+		},
+		get: func(c *Config) string { return strconv.FormatFloat(*field(c), 'g', -1, 64) },
+	}
+}
+
+// durationVar parses a Go duration such as 90s or 1h30m, which must be
+// positive if positive is set and non-negative otherwise.
+func durationVar(field func(*Config) *time.Duration, positive bool) binding {
+	return binding{
+		set: func(c *Config, v string) error {
+			d, err := time.ParseDuration(strings.TrimSpace(v))
+			switch {
+			case err != nil:
+				return errors.New("must be a duration such as 90s or 1h30m")
+			case positive && d <= 0:
+				return errors.New("must be positive")
+			case d < 0:
+				return errors.New("must not be negative")
+			}
+			*field(c) = d
+			return nil
+		},
+		get: func(c *Config) string { return formatDuration(*field(c)) },
+	}
+}
+
+func listVar(field func(*Config) *[]string) binding {
+	return binding{
+		set: func(c *Config, v string) error {
+			var list []string
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			*field(c) = list
+			return nil
+		},
+		get: func(c *Config) string { return strings.Join(*field(c), ",") },
+	}
+}
+
+func levelVar() binding {
+	return binding{
+		set: func(c *Config, v string) error {
+			l, err := logging.ParseLevel(strings.TrimSpace(v))
+			if err != nil {
+				return errors.New("want debug, info, warn or error")
+			}
+			c.LogLevel = l
+			return nil
+		},
+		get: func(c *Config) string { return logging.LevelName(c.LogLevel) },
+	}
+}
+
+func limitVar(field func(*Config) *ratelimit.Limit) binding {
+	return binding{
+		set: func(c *Config, v string) error {
+			l, err := ratelimit.ParseLimit(strings.TrimSpace(v))
+			if err != nil {
+				return err
+			}
+			*field(c) = l
+			return nil
+		},
+		get: func(c *Config) string {
+			if l := field(c); l.Requests > 0 {
+				return strconv.Itoa(l.Requests) + "/" + formatDuration(l.Per)
+			}
+			return "off"
+		},
+	}
+}
+
+func proxiesVar() binding {
+	return binding{
+		set: func(c *Config, v string) error {
+			proxies, err := ratelimit.ParseTrustedProxies(v)
+			if err != nil {
+				return err
+			}
+			c.TrustedProxies = proxies
+			return nil
+		},
+		get: func(c *Config) string {
+			var s []string
+			for _, p := range c.TrustedProxies {
+				s = append(s, p.String())
+			}
+			return strings.Join(s, ",")
+		},
+	}
+}
+
+func quotasVar() binding {
+	return binding{
+		set: func(c *Config, v string) error {
+			quotas, err := parseQuotas(v)
+			if err != nil {
+				return err
+			}
+			c.TenantQuotas = quotas
+			return nil
+		},
+		get: func(c *Config) string {
+			var s []string
+			for id, n := range c.TenantQuotas {
+				s = append(s, id+"="+strconv.Itoa(n))
+			}
+			slices.Sort(s)
+			return strings.Join(s, ",")
+		},
+	}
+}
+
+// parseQuotas parses a comma-separated list of tenant=limit pairs.
+func parseQuotas(s string) (map[string]int, error) {
+	quotas := make(map[string]int)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, limit, ok := strings.Cut(pair, "=")
+		if !ok || !tenant.Valid(id) {
+			return nil, fmt.Errorf("%q is not a tenant=limit pair", pair)
+		}
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("limit of tenant %s must be a non-negative integer", id)
+		}
+		quotas[id] = n
+	}
+	return quotas, nil
+}
+
+// formatDuration drops the zero units time.Duration.String leaves, so
+// 720h0m0s prints as 720h.
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+func maskAll(string) string {
+	return "********"
+}
+
+var dsnPassword = regexp.MustCompile(`(password=)[^\s&]+`)
+
+// maskDSN hides the passwords of a URL or key=value connection string.
+func maskDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		dsn = u.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}xxxxx")
+}
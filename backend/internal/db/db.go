@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/gowthamd/go-crud-app/internal/tracing"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -12,17 +13,29 @@ type DB struct {
 	Pool *pgxpool.Pool
 }
 
-func New(connString string) (*DB, error) {
+// Options size the connection pool. ConnectTimeout bounds the ping that
+// verifies the database is reachable.
+type Options struct {
+	MaxConns        int
+	MinConns        int
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+	ConnectTimeout  time.Duration
+}
+
+func New(connString string, opts Options) (*DB, error) {
 	config, err := pgxpool.ParseConfig(connString)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse database config: %w", err)
 	}
 
-	// Production grade settings
-	config.MaxConns = 25
-	config.MinConns = 2
-	config.MaxConnLifetime = 1 * time.Hour
-	config.MaxConnIdleTime = 30 * time.Minute
+	config.MaxConns = int32(opts.MaxConns)
+	config.MinConns = int32(opts.MinConns)
+	config.MaxConnLifetime = opts.MaxConnLifetime
+	config.MaxConnIdleTime = opts.MaxConnIdleTime
+
+	// Every query becomes a child span of the request that ran it.
+	config.ConnConfig.Tracer = tracing.QueryTracer{}
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
@@ -30,9 +43,10 @@ func New(connString string) (*DB, error) {
 	}
 
 	// Verify connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), opts.ConnectTimeout)
 	defer cancel()
 	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("unable to connect to database: %w", err)
 	}
 
@@ -46,3 +60,8 @@ func (db *DB) Close() {
 func (db *DB) Ping(ctx context.Context) error {
 	return db.Pool.Ping(ctx)
 }
+
+// Stat reports the connection pool's statistics.
+func (db *DB) Stat() *pgxpool.Stat {
+	return db.Pool.Stat()
+}
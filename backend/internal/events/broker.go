@@ -0,0 +1,87 @@
+// Package events fans the item change feed out to the change feed
+// subscribers connected to this replica.
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// it is disconnected.
+const subscriberBuffer = 64
+
+// Source delivers committed item events until ctx is done.
+type Source interface {
+	Listen(ctx context.Context, fn func(models.ItemEvent)) error
+}
+
+// Broker broadcasts item events to every subscriber. Publishing never
+// blocks: a subscriber that falls behind has its channel closed and is
+// expected to reconnect and resume from the last event it saw.
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[chan models.ItemEvent]struct{}
+	closed bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[chan models.ItemEvent]struct{})}
+}
+
+// Run publishes every event from src until ctx is done.
+func (b *Broker) Run(ctx context.Context, src Source) error {
+	return src.Listen(ctx, b.Publish)
+}
+
+// Subscribe returns a channel receiving every event published from now on
+// and a function that cancels the subscription. The channel is closed
+// when the subscriber falls behind or the broker is closed.
+func (b *Broker) Subscribe() (<-chan models.ItemEvent, func()) {
+	ch := make(chan models.ItemEvent, subscriberBuffer)
+	b.mu.Lock()
+	if b.closed {
+		close(ch)
+	} else {
+		b.subs[ch] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *Broker) Publish(e models.ItemEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close ends every subscription so that long-lived streams finish and the
+// server can shut down.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
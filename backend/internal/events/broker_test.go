@@ -0,0 +1,38 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+)
+
+func TestBroker_DropsSlowSubscriber(t *testing.T) {
+	b := NewBroker()
+	slow, _ := b.Subscribe()
+	fast, cancel := b.Subscribe()
+	defer cancel()
+
+	for n := 1; n <= subscriberBuffer+1; n++ {
+		b.Publish(models.ItemEvent{ID: int64(n)})
+		if e := <-fast; e.ID != int64(n) {
+			t.Fatalf("expected event %d, got %d", n, e.ID)
+		}
+	}
+
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("expected %d buffered events before the slow subscriber was closed, got %d", subscriberBuffer, received)
+	}
+
+	b.Close()
+	if _, ok := <-fast; ok {
+		t.Error("expected Close to end every subscription")
+	}
+	late, _ := b.Subscribe()
+	if _, ok := <-late; ok {
+		t.Error("expected subscriptions after Close to be closed")
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gowthamd/go-crud-app/internal/auth"
+	"github.com/gowthamd/go-crud-app/internal/identity"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+	"github.com/gowthamd/go-crud-app/internal/repository"
+)
+
+// APIKeyHandler manages the API keys of machine clients. Scopes lists the
+// permissions a key may be granted; when Policy is set, callers may only
+// grant the permissions they hold themselves.
+type APIKeyHandler struct {
+	Repo   repository.APIKeyStore
+	Scopes []string
+	Policy auth.Policy
+}
+
+func NewAPIKeyHandler(repo repository.APIKeyStore, scopes []string, policy auth.Policy) *APIKeyHandler {
+	return &APIKeyHandler{Repo: repo, Scopes: scopes, Policy: policy}
+}
+
+// CreateAPIKey issues a key. The response is the only one that includes
+// the key; only its hash is stored.
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var dto models.CreateAPIKeyDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := dto.Validate(h.Scopes); err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+	if h.Policy != nil {
+		caller, _ := identity.FromContext(r.Context())
+		for _, scope := range dto.Scopes {
+			if caller == nil || !h.Policy.Allows(caller, scope) {
+				problem.Error(w, r, http.StatusForbidden, fmt.Sprintf("Cannot grant permission %q you do not hold", scope))
+				return
+			}
+		}
+	}
+
+	secret, prefix := auth.NewAPIKey()
+	key, err := h.Repo.CreateAPIKey(r.Context(), &models.APIKey{
+		Name:      dto.Name,
+		Prefix:    prefix,
+		Hash:      auth.HashAPIKey(secret),
+		Scopes:    dto.Scopes,
+		CreatedBy: identity.Actor(r.Context()),
+		ExpiresAt: dto.ExpiresAt,
+	})
+	if err != nil {
+		problem.Internal(w, r, err, "Failed to create API key")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.CreatedAPIKey{APIKey: *key, Key: secret})
+}
+
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.Repo.ListAPIKeys(r.Context())
+	if err != nil {
+		problem.Internal(w, r, err, "Failed to list API keys")
+		return
+	}
+
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// DeleteAPIKey revokes a key immediately.
+func (h *APIKeyHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Repo.DeleteAPIKey(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			problem.Error(w, r, http.StatusNotFound, "API key not found")
+			return
+		}
+		problem.Internal(w, r, err, "Failed to delete API key")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
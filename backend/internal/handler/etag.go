@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+	"github.com/gowthamd/go-crud-app/internal/repository"
+)
+
+var errPreconditionFailed = errors.New("precondition failed")
+
+// itemETag derives a strong entity tag from the item version.
+func itemETag(i *models.Item) string {
+	return `"` + strconv.FormatInt(i.Version, 10) + `"`
+}
+
+// splitETags splits an If-Match / If-None-Match header into its tags.
+func splitETags(header string) []string {
+	var tags []string
+	for _, t := range strings.Split(header, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// noneMatch reports whether an If-None-Match header matches etag using
+// weak comparison, meaning the client's copy is current.
+func noneMatch(header, etag string) bool {
+	for _, t := range splitETags(header) {
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion resolves the If-Match header of a write into the item
+// version it must apply to. It returns 0 when the write is unconditional
+// and errPreconditionFailed when no listed tag can match.
+func (h *ItemHandler) ifMatchVersion(ctx context.Context, r *http.Request, id uuid.UUID) (int64, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, nil
+	}
+
+	var versions []int64
+	for _, t := range splitETags(header) {
+		if t == "*" {
+			return 0, nil
+		}
+		// If-Match uses strong comparison, so weak tags never match.
+		if len(t) < 2 || t[0] != '"' || t[len(t)-1] != '"' {
+			continue
+		}
+		if v, err := strconv.ParseInt(t[1:len(t)-1], 10, 64); err == nil && v > 0 {
+			versions = append(versions, v)
+		}
+	}
+
+	switch len(versions) {
+	case 0:
+		return 0, errPreconditionFailed
+	case 1:
+		return versions[0], nil
+	}
+
+	// Several candidate tags: pick the one naming the current version and
+	// let the store enforce it atomically.
+	item, err := h.Repo.GetByID(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	for _, v := range versions {
+		if v == item.Version {
+			return v, nil
+		}
+	}
+	return 0, errPreconditionFailed
+}
+
+// writeConditionalError maps the errors of a conditional write to a status.
+func writeConditionalError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	var invalid models.ValidationErrors
+	if errors.As(err, &invalid) {
+		problem.Validation(w, r, err)
+		return
+	}
+	status, detail := conditionalError(err, fallback)
+	if status == http.StatusInternalServerError {
+		problem.Internal(w, r, err, detail)
+		return
+	}
+	problem.Error(w, r, status, detail)
+}
+
+// conditionalError maps a conditional write failure to a status and detail.
+func conditionalError(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, repository.ErrItemNotFound):
+		return http.StatusNotFound, "Item not found"
+	case errors.Is(err, errPreconditionFailed), errors.Is(err, repository.ErrVersionConflict):
+		return http.StatusPreconditionFailed, "Item has been modified"
+	case errors.Is(err, repository.ErrQuotaExceeded):
+		return http.StatusForbidden, quotaExceeded
+	case errors.As(err, new(models.ValidationErrors)):
+		return http.StatusBadRequest, "Validation failed"
+	default:
+		return http.StatusInternalServerError, fallback
+	}
+}
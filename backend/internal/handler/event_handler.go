@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/internal/events"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+	"github.com/gowthamd/go-crud-app/internal/repository"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+)
+
+// heartbeatInterval keeps idle change feed connections open through
+// proxies and load balancers.
+const heartbeatInterval = 15 * time.Second
+
+// eventReplayBatch is the page size used to replay events a reconnecting
+// client missed.
+const eventReplayBatch = 500
+
+type EventHandler struct {
+	Repo   repository.ItemStore
+	Broker *events.Broker
+}
+
+func NewEventHandler(repo repository.ItemStore, broker *events.Broker) *EventHandler {
+	return &EventHandler{Repo: repo, Broker: broker}
+}
+
+// StreamItemEvents serves the item change feed as Server-Sent Events. A
+// client that sends Last-Event-ID (or last_event_id, for clients that
+// cannot set headers) first receives the events after it. The stream
+// ends when the client falls too far behind; it is expected to reconnect.
+// Only events of the request's tenant are sent.
+//
+// Event ids are taken when a revision is written, not when it commits, so
+// a write can commit after one with a higher id. Such a late event is
+// still sent to connected clients, but one committing while a client is
+// disconnected is not replayed when it resumes past its id: across
+// reconnects the feed delivers each event at most once.
+func (h *EventHandler) StreamItemEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		problem.Error(w, r, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	var lastID int64
+	if raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id < 0 {
+			problem.Error(w, r, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+		lastID = id
+	}
+
+	// Subscribe before replaying so nothing committed in between is lost.
+	live, cancel := h.Broker.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Live events are told apart from replayed ones by id rather than by
+	// comparing with the last replayed id, which would drop late events.
+	tenantID := tenant.ID(r.Context())
+	replayedTo := lastID
+	replayed := make(map[int64]bool)
+	for raw != "" {
+		batch, err := h.Repo.EventsSince(r.Context(), replayedTo, eventReplayBatch)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to replay item events", "error", err)
+			return
+		}
+		for n := range batch {
+			if err := writeEvent(w, &batch[n]); err != nil {
+				return
+			}
+			replayedTo = batch[n].ID
+			replayed[replayedTo] = true
+		}
+		if len(batch) < eventReplayBatch {
+			break
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-live:
+			if !ok {
+				return
+			}
+			if e.Tenant != tenantID {
+				continue
+			}
+			if replayed[e.ID] {
+				delete(replayed, e.ID)
+				continue
+			}
+			if err := writeEvent(w, &e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes e as one SSE message named after its action.
+func writeEvent(w io.Writer, e *models.ItemEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Action, data)
+	return err
+}
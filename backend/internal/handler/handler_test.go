@@ -1,14 +1,20 @@
 package handler
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/gowthamd/go-crud-app/internal/events"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/problem"
 	"github.com/gowthamd/go-crud-app/internal/repository"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
 )
 
 func TestCreateItem_InvalidJSON(t *testing.T) {
@@ -113,6 +119,29 @@ func TestUpdateItem_EmptyName(t *testing.T) {
 	}
 }
 
+func TestUpdateItem_PricesRepeatStoredCurrency(t *testing.T) {
+	repo := repository.NewMemoryItemRepository()
+	h := NewItemHandler(repo)
+	item, err := repo.Create(context.Background(), &models.CreateItemDTO{Name: "Widget", Price: models.MustParseMoney("5"), Currency: "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/items/"+item.ID.String(), strings.NewReader(`{"prices":{"eur":9,"CHF":5.25}}`))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", item.ID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+
+	h.UpdateItem(rec, req)
+
+	var p problem.Details
+	json.NewDecoder(rec.Body).Decode(&p)
+	if rec.Code != http.StatusBadRequest || p.Type != problem.TypeValidation || len(p.Errors) != 1 || p.Errors[0].Field != "prices.EUR" {
+		t.Errorf("expected a prices.EUR field error, got %d %+v", rec.Code, p)
+	}
+}
+
 func TestDeleteItem_InvalidUUID(t *testing.T) {
 	h := NewItemHandler(&repository.ItemRepository{})
 
@@ -125,3 +154,186 @@ func TestDeleteItem_InvalidUUID(t *testing.T) {
 		t.Errorf("expected status 400, got %d", rec.Code)
 	}
 }
+
+func TestListItems_InvalidQuery(t *testing.T) {
+	h := NewItemHandler(repository.NewMemoryItemRepository())
+
+	for _, query := range []string{
+		"sort=password",
+		"min_price=cheap",
+		"min_price=10&max_price=5",
+		"created_after=yesterday",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/items?"+query, nil)
+		rec := httptest.NewRecorder()
+
+		h.ListItems(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestCreateItem_ProblemDetails(t *testing.T) {
+	h := NewItemHandler(&repository.ItemRepository{})
+
+	body := `{"name":"","price":-5}`
+	req := httptest.NewRequest(http.MethodPost, "/api/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.CreateItem(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Errorf("expected content type %q, got %q", problem.ContentType, ct)
+	}
+
+	var p problem.Details
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatalf("invalid problem body: %v", err)
+	}
+	if p.Status != http.StatusBadRequest || p.Type != problem.TypeValidation || p.Instance != "/api/items" {
+		t.Errorf("unexpected problem %+v", p)
+	}
+	if len(p.Errors) != 2 || p.Errors[0].Field != "name" || p.Errors[1].Field != "price" {
+		t.Errorf("expected name and price field errors, got %+v", p.Errors)
+	}
+}
+
+func TestImportItems(t *testing.T) {
+	repo := repository.NewMemoryItemRepository()
+	h := NewItemHandler(repo)
+
+	csvBody := "name,price,currency,price_eur\n" +
+		"Widget,9.99,USD,9.20\n" +
+		",abc,USD,\n" +
+		"Gadget,5,GBP,oops\n" +
+		"Gizmo,1.50,,\n"
+
+	importCSV := func(query string) models.ImportResult {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/items/import"+query, strings.NewReader(csvBody))
+		req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+		rec := httptest.NewRecorder()
+		h.ImportItems(rec, req)
+
+		var result models.ImportResult
+		if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+			t.Fatalf("invalid result body: %v", err)
+		}
+		return result
+	}
+
+	result := importCSV("?dry_run=true")
+	if result.Rows != 4 || result.Imported != 2 || result.Failed != 2 {
+		t.Fatalf("unexpected dry run result %+v", result)
+	}
+	if result.Errors[0].Row != 3 || len(result.Errors[0].Errors) != 2 || result.Errors[1].Row != 4 {
+		t.Errorf("unexpected row errors %+v", result.Errors)
+	}
+	if _, total, _ := repo.GetAll(context.Background(), &models.ItemListQuery{Limit: 10}); total != 0 {
+		t.Fatalf("dry run wrote %d items", total)
+	}
+
+	if result := importCSV(""); result.Imported != 2 {
+		t.Fatalf("expected 2 imported rows, got %+v", result)
+	}
+	items, total, _ := repo.GetAll(context.Background(), &models.ItemListQuery{Limit: 10})
+	if total != 2 {
+		t.Fatalf("expected 2 stored items, got %d", total)
+	}
+	for _, item := range items {
+		if item.Name == "Widget" && !item.Prices["EUR"].Equal(models.MustParseMoney("9.20")) {
+			t.Errorf("expected EUR price list entry, got %v", item.Prices)
+		}
+	}
+}
+
+func TestImportItems_NDJSON(t *testing.T) {
+	h := NewItemHandler(repository.NewMemoryItemRepository())
+
+	body := `{"name":"Widget","price":1}` + "\n\n" + `not json` + "\n" + `{"name":"Gadget","price":-1}` + "\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/items/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	h.ImportItems(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", rec.Code)
+	}
+	var result models.ImportResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 1 || len(result.Errors) != 2 || result.Errors[0].Row != 3 || result.Errors[1].Row != 4 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/items/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	h.ImportItems(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected status 415, got %d", rec.Code)
+	}
+}
+
+// replayStore serves a fixed change feed.
+type replayStore struct {
+	repository.ItemStore
+	events []models.ItemEvent
+}
+
+func (s replayStore) EventsSince(_ context.Context, afterID int64, _ int) ([]models.ItemEvent, error) {
+	var out []models.ItemEvent
+	for _, e := range s.events {
+		if e.ID > afterID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func TestStreamItemEvents_LateCommit(t *testing.T) {
+	event := func(id int64) models.ItemEvent {
+		return models.ItemEvent{ID: id, Tenant: tenant.Default, ItemRevision: models.ItemRevision{Action: models.ActionUpdated}}
+	}
+	broker := events.NewBroker()
+	h := NewEventHandler(replayStore{events: []models.ItemEvent{event(1), event(3)}}, broker)
+	srv := httptest.NewServer(http.HandlerFunc(h.StreamItemEvents))
+	defer srv.Close()
+	defer broker.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+	nextID := func() string {
+		t.Helper()
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading event stream: %v", err)
+			}
+			if strings.HasPrefix(line, "id: ") {
+				return strings.TrimSpace(strings.TrimPrefix(line, "id: "))
+			}
+		}
+	}
+	if a, b := nextID(), nextID(); a != "1" || b != "3" {
+		t.Fatalf("expected events 1 and 3 to be replayed, got %s and %s", a, b)
+	}
+
+	// 3 was replayed already; 2 committed after it and has not been sent.
+	broker.Publish(event(3))
+	broker.Publish(event(2))
+	broker.Publish(event(4))
+	if a, b := nextID(), nextID(); a != "2" || b != "4" {
+		t.Errorf("expected live events 2 and 4, got %s and %s", a, b)
+	}
+}
@@ -1,16 +1,22 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 
-	"github.com/gowthamd/go-crud-app/internal/db"
+	"github.com/gowthamd/go-crud-app/internal/problem"
 )
 
+// Pinger reports whether a backing store is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
 type HealthHandler struct {
-	DB *db.DB
+	DB Pinger
 }
 
-func NewHealthHandler(db *db.DB) *HealthHandler {
+func NewHealthHandler(db Pinger) *HealthHandler {
 	return &HealthHandler{DB: db}
 }
 
@@ -21,7 +27,7 @@ func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
 
 func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
 	if err := h.DB.Ping(r.Context()); err != nil {
-		http.Error(w, "Database unavailable", http.StatusServiceUnavailable)
+		problem.Error(w, r, http.StatusServiceUnavailable, "Database unavailable")
 		return
 	}
 	w.WriteHeader(http.StatusOK)
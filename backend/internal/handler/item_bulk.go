@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+	"github.com/gowthamd/go-crud-app/internal/repository"
+)
+
+// BulkItems applies a batch of create, update and delete operations. In
+// atomic mode any invalid or failing operation rejects the whole batch;
+// in best-effort mode every operation gets its own result.
+func (h *ItemHandler) BulkItems(w http.ResponseWriter, r *http.Request) {
+	var req models.BulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+	atomic := req.Mode == models.BulkAtomic
+
+	resp := models.BulkResponse{Mode: req.Mode, Results: make([]models.BulkResult, len(req.Operations))}
+	var invalid models.ValidationErrors
+	var ops []models.BulkOperation
+	var indexes []int
+	for n := range req.Operations {
+		op := &req.Operations[n]
+		resp.Results[n] = models.BulkResult{Index: n, Op: op.Op}
+		if err := op.Validate(); err != nil {
+			fields := fieldErrors(err)
+			for _, f := range fields {
+				invalid.Add(fmt.Sprintf("operations[%d].%s", n, f.Field), f.Message)
+			}
+			resp.Results[n].Status = http.StatusBadRequest
+			resp.Results[n].Error = "Validation failed"
+			resp.Results[n].Errors = fields
+			continue
+		}
+		ops = append(ops, *op)
+		indexes = append(indexes, n)
+	}
+
+	if atomic && len(invalid) > 0 {
+		problem.Validation(w, r, invalid)
+		return
+	}
+
+	outcomes, err := h.Repo.Bulk(r.Context(), ops, atomic)
+	if err != nil {
+		var bulkErr *repository.BulkError
+		if errors.As(err, &bulkErr) {
+			status, detail := conditionalError(bulkErr.Err, "Failed to apply operation")
+			if status == http.StatusInternalServerError {
+				slog.ErrorContext(r.Context(), "Failed to apply bulk operation", "operation", indexes[bulkErr.Index], "error", bulkErr.Err)
+			}
+			p := problem.New(status, fmt.Sprintf("Operation %d failed: %s; no changes were applied", indexes[bulkErr.Index], detail))
+			p.Errors = []models.FieldError{{Field: fmt.Sprintf("operations[%d]", indexes[bulkErr.Index]), Message: detail}}
+			if status == http.StatusBadRequest {
+				p.Errors = nil
+				for _, f := range fieldErrors(bulkErr.Err) {
+					p.Errors = append(p.Errors, models.FieldError{Field: fmt.Sprintf("operations[%d].%s", indexes[bulkErr.Index], f.Field), Message: f.Message})
+				}
+			}
+			problem.Write(w, r, p)
+			return
+		}
+		problem.Internal(w, r, err, "Failed to apply bulk operations")
+		return
+	}
+
+	for k, outcome := range outcomes {
+		res := &resp.Results[indexes[k]]
+		op := &ops[k]
+		if op.Op != models.BulkCreate {
+			res.ID = &op.ID
+		}
+		if outcome.Err != nil {
+			res.Status, res.Error = conditionalError(outcome.Err, "Failed to apply operation")
+			if res.Status == http.StatusBadRequest {
+				res.Errors = fieldErrors(outcome.Err)
+			}
+			if res.Status == http.StatusInternalServerError {
+				slog.ErrorContext(r.Context(), "Failed to apply bulk operation", "operation", indexes[k], "error", outcome.Err)
+			}
+			continue
+		}
+
+		res.Item = outcome.Item
+		switch op.Op {
+		case models.BulkCreate:
+			res.Status = http.StatusCreated
+			res.ID = &outcome.Item.ID
+		case models.BulkUpdate:
+			res.Status = http.StatusOK
+		case models.BulkDelete:
+			res.Status = http.StatusNoContent
+		}
+	}
+
+	for _, res := range resp.Results {
+		if res.Status < 300 {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// fieldErrors returns the field errors carried by a validation error.
+func fieldErrors(err error) models.ValidationErrors {
+	var fields models.ValidationErrors
+	if errors.As(err, &fields) {
+		return fields
+	}
+	return models.ValidationErrors{{Field: "item", Message: err.Error()}}
+}
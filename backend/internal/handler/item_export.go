@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+)
+
+// exportWriter encodes items in one download format.
+type exportWriter interface {
+	Write(item *models.Item) error
+	// Close finishes the document; it is called even when nothing was
+	// written.
+	Close() error
+}
+
+var exportFormats = map[string]struct {
+	contentType string
+	newWriter   func(io.Writer) exportWriter
+}{
+	"csv":    {"text/csv; charset=utf-8", newCSVExportWriter},
+	"ndjson": {"application/x-ndjson", newNDJSONExportWriter},
+	"json":   {"application/json", newJSONExportWriter},
+}
+
+// ExportItems streams every item matching the list filters as a download.
+// Once the first row is sent a failure can no longer be reported as a
+// problem, so the connection is aborted to leave the file visibly
+// truncated.
+func (h *ItemHandler) ExportItems(w http.ResponseWriter, r *http.Request) {
+	var errs models.ValidationErrors
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = "csv"
+	}
+	format, ok := exportFormats[name]
+	if !ok {
+		errs.Add("format", "format must be csv, ndjson or json")
+	}
+
+	filter, err := parseItemFilter(r.URL.Query())
+	if err != nil {
+		errs = append(errs, fieldErrors(err)...)
+	}
+	sort, err := parseSortParam(r.URL.Query())
+	if err != nil {
+		errs = append(errs, fieldErrors(err)...)
+	}
+	if err := errs.Err(); err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+
+	var enc exportWriter
+	start := func() {
+		filename := fmt.Sprintf("items-%s.%s", time.Now().UTC().Format("20060102T150405Z"), name)
+		w.Header().Set("Content-Type", format.contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		enc = format.newWriter(w)
+	}
+
+	err = h.Repo.Export(r.Context(), &filter, sort, func(item *models.Item) error {
+		if enc == nil {
+			start()
+		}
+		return enc.Write(item)
+	})
+	if err != nil {
+		if enc == nil {
+			problem.Internal(w, r, err, "Failed to export items")
+			return
+		}
+		slog.ErrorContext(r.Context(), "Export aborted", "error", err)
+		panic(http.ErrAbortHandler)
+	}
+
+	if enc == nil {
+		start()
+	}
+	if err := enc.Close(); err != nil {
+		slog.ErrorContext(r.Context(), "Export aborted", "error", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// csvExportColumns is the header row of CSV exports. The price list is
+// written as a JSON object in a single column.
+var csvExportColumns = []string{"id", "name", "price", "currency", "prices", "version", "created_at", "updated_at", "deleted_at"}
+
+type csvExportWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVExportWriter(w io.Writer) exportWriter {
+	return &csvExportWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvExportWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.w.Write(csvExportColumns)
+}
+
+func (c *csvExportWriter) Write(item *models.Item) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	prices := ""
+	if len(item.Prices) > 0 {
+		b, err := json.Marshal(item.Prices)
+		if err != nil {
+			return err
+		}
+		prices = string(b)
+	}
+	deletedAt := ""
+	if item.DeletedAt != nil {
+		deletedAt = item.DeletedAt.Format(time.RFC3339Nano)
+	}
+
+	return c.w.Write([]string{
+		item.ID.String(),
+		item.Name,
+		item.Price.String(),
+		item.Currency,
+		prices,
+		strconv.FormatInt(item.Version, 10),
+		item.CreatedAt.Format(time.RFC3339Nano),
+		item.UpdatedAt.Format(time.RFC3339Nano),
+		deletedAt,
+	})
+}
+
+func (c *csvExportWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonExportWriter struct {
+	enc *json.Encoder
+}
+
+func newNDJSONExportWriter(w io.Writer) exportWriter {
+	return &ndjsonExportWriter{enc: json.NewEncoder(w)}
+}
+
+func (n *ndjsonExportWriter) Write(item *models.Item) error { return n.enc.Encode(item) }
+
+func (n *ndjsonExportWriter) Close() error { return nil }
+
+// jsonExportWriter writes a single JSON array, one element per line.
+type jsonExportWriter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func newJSONExportWriter(w io.Writer) exportWriter {
+	return &jsonExportWriter{w: w, enc: json.NewEncoder(w)}
+}
+
+func (j *jsonExportWriter) Write(item *models.Item) error {
+	sep := ",\n"
+	if j.count == 0 {
+		sep = "[\n"
+	}
+	j.count++
+	if _, err := io.WriteString(j.w, sep); err != nil {
+		return err
+	}
+	return j.enc.Encode(item)
+}
+
+func (j *jsonExportWriter) Close() error {
+	end := "]\n"
+	if j.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
@@ -3,42 +3,53 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/problem"
 	"github.com/gowthamd/go-crud-app/internal/repository"
 )
 
 type ItemHandler struct {
-	Repo *repository.ItemRepository
+	Repo repository.ItemStore
 }
 
-func NewItemHandler(repo *repository.ItemRepository) *ItemHandler {
+// quotaExceeded is the detail of writes refused by the tenant's item quota.
+const quotaExceeded = "The tenant's item quota has been reached"
+
+func NewItemHandler(repo repository.ItemStore) *ItemHandler {
 	return &ItemHandler{Repo: repo}
 }
 
 func (h *ItemHandler) CreateItem(w http.ResponseWriter, r *http.Request) {
 	var dto models.CreateItemDTO
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
 
 	if err := dto.Validate(); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		problem.Validation(w, r, err)
 		return
 	}
 
 	item, err := h.Repo.Create(r.Context(), &dto)
 	if err != nil {
-		http.Error(w, "Failed to create item", http.StatusInternalServerError)
+		if errors.Is(err, repository.ErrQuotaExceeded) {
+			problem.Error(w, r, http.StatusForbidden, quotaExceeded)
+			return
+		}
+		problem.Internal(w, r, err, "Failed to create item")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", itemETag(item))
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(item)
 }
@@ -47,17 +58,29 @@ func (h *ItemHandler) GetItem(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if r.URL.Query().Has("as_of") {
+		h.getItemAsOf(w, r, id)
 		return
 	}
 
 	item, err := h.Repo.GetByID(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, repository.ErrItemNotFound) {
-			http.Error(w, "Item not found", http.StatusNotFound)
+			problem.Error(w, r, http.StatusNotFound, "Item not found")
 			return
 		}
-		http.Error(w, "Failed to retrieve item", http.StatusInternalServerError)
+		problem.Internal(w, r, err, "Failed to retrieve item")
+		return
+	}
+
+	etag := itemETag(item)
+	w.Header().Set("ETag", etag)
+	if noneMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
@@ -76,9 +99,33 @@ func (h *ItemHandler) ListItems(w http.ResponseWriter, r *http.Request) {
 		offset = 0
 	}
 
-	items, total, err := h.Repo.GetAll(r.Context(), limit, offset)
+	filter, err := parseItemFilter(r.URL.Query())
+	if err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+
+	if r.URL.Query().Has("cursor") {
+		h.listItemsByCursor(w, r, &filter, limit)
+		return
+	}
+
+	sort, err := parseSortParam(r.URL.Query())
+	if err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+
+	query := &models.ItemListQuery{
+		Filter: filter,
+		Sort:   sort,
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	items, total, err := h.Repo.GetAll(r.Context(), query)
 	if err != nil {
-		http.Error(w, "Failed to list items", http.StatusInternalServerError)
+		problem.Internal(w, r, err, "Failed to list items")
 		return
 	}
 
@@ -97,36 +144,113 @@ func (h *ItemHandler) ListItems(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// listItemsByCursor serves keyset pagination. It skips the COUNT(*) and
+// advertises neighbouring pages in the body and in RFC 5988 Link headers.
+func (h *ItemHandler) listItemsByCursor(w http.ResponseWriter, r *http.Request, filter *models.ItemFilter, limit int) {
+	q := r.URL.Query()
+	if q.Has("sort") || q.Has("offset") {
+		problem.Error(w, r, http.StatusBadRequest, "cursor pagination cannot be combined with sort or offset")
+		return
+	}
+
+	var cursor *models.Cursor
+	if raw := q.Get("cursor"); raw != "" {
+		c, err := models.DecodeCursor(raw)
+		if err != nil {
+			problem.Error(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		cursor = c
+	}
+
+	items, hasMore, err := h.Repo.GetPage(r.Context(), filter, cursor, limit)
+	if err != nil {
+		problem.Internal(w, r, err, "Failed to list items")
+		return
+	}
+
+	if items == nil {
+		items = []models.Item{}
+	}
+
+	resp := models.CursorPage{Items: items, Limit: limit}
+	backward := cursor != nil && cursor.Backward
+
+	// Moving forward, a previous page exists whenever we started from a
+	// cursor; moving backward, a next page always exists.
+	if backward || hasMore {
+		next := models.Cursor{}
+		if len(items) > 0 {
+			next.CreatedAt, next.ID = items[len(items)-1].CreatedAt, items[len(items)-1].ID
+		} else {
+			next.CreatedAt, next.ID = cursor.CreatedAt, cursor.ID
+		}
+		resp.NextCursor = next.Encode()
+	}
+	if (backward && hasMore) || (!backward && cursor != nil) {
+		prev := models.Cursor{Backward: true}
+		if len(items) > 0 {
+			prev.CreatedAt, prev.ID = items[0].CreatedAt, items[0].ID
+		} else {
+			prev.CreatedAt, prev.ID = cursor.CreatedAt, cursor.ID
+		}
+		resp.PrevCursor = prev.Encode()
+	}
+
+	if resp.NextCursor != "" {
+		w.Header().Add("Link", cursorLink(r, resp.NextCursor, "next"))
+	}
+	if resp.PrevCursor != "" {
+		w.Header().Add("Link", cursorLink(r, resp.PrevCursor, "prev"))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// cursorLink renders a Link header value pointing at the current request
+// with its cursor replaced.
+func cursorLink(r *http.Request, cursor, rel string) string {
+	u := url.URL{Path: r.URL.Path}
+	q := r.URL.Query()
+	q.Set("cursor", cursor)
+	u.RawQuery = q.Encode()
+	return fmt.Sprintf("<%s>; rel=\"%s\"", u.String(), rel)
+}
+
 func (h *ItemHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, "Invalid ID format")
 		return
 	}
 
 	var dto models.UpdateItemDTO
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
 
 	if err := dto.Validate(); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		problem.Validation(w, r, err)
 		return
 	}
 
-	item, err := h.Repo.Update(r.Context(), id, &dto)
+	version, err := h.ifMatchVersion(r.Context(), r, id)
 	if err != nil {
-		if errors.Is(err, repository.ErrItemNotFound) {
-			http.Error(w, "Item not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to update item", http.StatusInternalServerError)
+		writeConditionalError(w, r, err, "Failed to update item")
+		return
+	}
+
+	item, err := h.Repo.Update(r.Context(), id, &dto, version)
+	if err != nil {
+		writeConditionalError(w, r, err, "Failed to update item")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", itemETag(item))
 	json.NewEncoder(w).Encode(item)
 }
 
@@ -134,18 +258,47 @@ func (h *ItemHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, "Invalid ID format")
 		return
 	}
 
-	if err := h.Repo.Delete(r.Context(), id); err != nil {
+	version, err := h.ifMatchVersion(r.Context(), r, id)
+	if err != nil {
+		writeConditionalError(w, r, err, "Failed to delete item")
+		return
+	}
+
+	if err := h.Repo.Delete(r.Context(), id, version); err != nil {
+		writeConditionalError(w, r, err, "Failed to delete item")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ItemHandler) RestoreItem(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	item, err := h.Repo.Restore(r.Context(), id)
+	if err != nil {
 		if errors.Is(err, repository.ErrItemNotFound) {
-			http.Error(w, "Item not found", http.StatusNotFound)
+			problem.Error(w, r, http.StatusNotFound, "No deleted item with this ID")
 			return
 		}
-		http.Error(w, "Failed to delete item", http.StatusInternalServerError)
+		if errors.Is(err, repository.ErrQuotaExceeded) {
+			problem.Error(w, r, http.StatusForbidden, quotaExceeded)
+			return
+		}
+		problem.Internal(w, r, err, "Failed to restore item")
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", itemETag(item))
+	json.NewEncoder(w).Encode(item)
 }
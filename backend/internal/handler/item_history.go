@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+	"github.com/gowthamd/go-crud-app/internal/repository"
+)
+
+func (h *ItemHandler) GetItemHistory(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	revisions, err := h.Repo.History(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrItemNotFound) {
+			problem.Error(w, r, http.StatusNotFound, "Item not found")
+			return
+		}
+		problem.Internal(w, r, err, "Failed to retrieve item history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.ItemHistory{ItemID: id, Revisions: revisions})
+}
+
+// getItemAsOf serves GET /api/items/{id}?as_of=... from the revision
+// history. Historical views carry no ETag since they cannot be updated.
+func (h *ItemHandler) getItemAsOf(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	var errs models.ValidationErrors
+	at := parseTimeParam(r.URL.Query(), "as_of", &errs)
+	if at == nil && len(errs) == 0 {
+		errs.Add("as_of", "as_of must not be empty")
+	}
+	if err := errs.Err(); err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+
+	item, err := h.Repo.GetAsOf(r.Context(), id, *at)
+	if err != nil {
+		if errors.Is(err, repository.ErrItemNotFound) {
+			problem.Error(w, r, http.StatusNotFound, "Item did not exist at the requested time")
+			return
+		}
+		problem.Internal(w, r, err, "Failed to retrieve item")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+func (h *ItemHandler) RevertItem(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	target, err := strconv.ParseInt(chi.URLParam(r, "version"), 10, 64)
+	if err != nil || target < 1 {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid revision version")
+		return
+	}
+
+	version, err := h.ifMatchVersion(r.Context(), r, id)
+	if err != nil {
+		writeConditionalError(w, r, err, "Failed to revert item")
+		return
+	}
+
+	item, err := h.Repo.Revert(r.Context(), id, target, version)
+	if err != nil {
+		if errors.Is(err, repository.ErrRevisionNotFound) {
+			problem.Error(w, r, http.StatusNotFound, "Revision not found")
+			return
+		}
+		writeConditionalError(w, r, err, "Failed to revert item")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", itemETag(item))
+	json.NewEncoder(w).Encode(item)
+}
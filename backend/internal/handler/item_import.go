@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+	"github.com/gowthamd/go-crud-app/internal/repository"
+)
+
+// maxImportBytes bounds the size of an uploaded import file.
+const maxImportBytes = 32 << 20
+
+// importRow is one decoded row of an import file. Errors holds decoding
+// failures; Malformed rows could not be read as an item at all and skip
+// validation.
+type importRow struct {
+	Line      int
+	Item      models.CreateItemDTO
+	Errors    models.ValidationErrors
+	Malformed bool
+}
+
+// errTooManyRows aborts decoding once MaxImportRows is exceeded.
+var errTooManyRows = fmt.Errorf("import must not contain more than %d rows", models.MaxImportRows)
+
+// ImportItems creates items from a CSV or NDJSON upload. Rows failing
+// validation are reported and skipped; with dry_run=true nothing is
+// written.
+func (h *ItemHandler) ImportItems(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if raw := r.URL.Query().Get("dry_run"); raw != "" {
+		switch raw {
+		case "true", "1":
+			dryRun = true
+		case "false", "0":
+		default:
+			problem.Validation(w, r, models.ValidationErrors{{Field: "dry_run", Message: "dry_run must be true or false"}})
+			return
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var decode func(io.Reader, func(importRow) error) error
+	switch mediaType {
+	case "text/csv":
+		decode = decodeCSVRows
+	case "application/x-ndjson", "application/ndjson":
+		decode = decodeNDJSONRows
+	default:
+		problem.Error(w, r, http.StatusUnsupportedMediaType, "Content-Type must be text/csv or application/x-ndjson")
+		return
+	}
+
+	result := models.ImportResult{DryRun: dryRun}
+	var items []models.CreateItemDTO
+	err := decode(http.MaxBytesReader(w, r.Body, maxImportBytes), func(row importRow) error {
+		result.Rows++
+		if result.Rows > models.MaxImportRows {
+			return errTooManyRows
+		}
+		if !row.Malformed {
+			if err := row.Item.Validate(); err != nil {
+				row.Errors = append(row.Errors, fieldErrors(err)...)
+			}
+		}
+		if len(row.Errors) > 0 {
+			result.Failed++
+			result.Errors = append(result.Errors, models.ImportRowError{Row: row.Line, Errors: row.Errors})
+			return nil
+		}
+		items = append(items, row.Item)
+		return nil
+	})
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			problem.Error(w, r, http.StatusRequestEntityTooLarge, "Import file is too large")
+		case errors.Is(err, errTooManyRows):
+			problem.Error(w, r, http.StatusRequestEntityTooLarge, err.Error())
+		default:
+			problem.Error(w, r, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	result.Imported = len(items)
+	if !dryRun && len(items) > 0 {
+		if _, err := h.Repo.Import(r.Context(), items); err != nil {
+			if errors.Is(err, repository.ErrQuotaExceeded) {
+				problem.Error(w, r, http.StatusForbidden, quotaExceeded)
+				return
+			}
+			problem.Internal(w, r, err, "Failed to import items")
+			return
+		}
+	}
+
+	status := http.StatusOK
+	if !dryRun && result.Imported > 0 {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// decodeCSVRows reads a CSV file whose header names the columns name,
+// price, currency and optionally price_<CODE> for the price list.
+func decodeCSVRows(body io.Reader, emit func(importRow) error) error {
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	columns := make([]string, len(header))
+	seen := make(map[string]bool)
+	for n, name := range header {
+		if n == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "name", name == "price", name == "currency":
+		case strings.HasPrefix(name, "price_") && len(name) > len("price_"):
+		default:
+			return fmt.Errorf("unknown CSV column %q", header[n])
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate CSV column %q", header[n])
+		}
+		seen[name] = true
+		columns[n] = name
+	}
+	if !seen["name"] || !seen["price"] {
+		return errors.New("CSV header must include name and price columns")
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		var row importRow
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			row = importRow{Line: parseErr.StartLine, Malformed: true}
+			row.Errors.Add("row", "row is not valid CSV: "+parseErr.Err.Error())
+		case err != nil:
+			return err
+		default:
+			row.Line, _ = cr.FieldPos(0)
+			if len(record) != len(columns) {
+				row.Malformed = true
+				row.Errors.Add("row", fmt.Sprintf("row has %d fields, header has %d", len(record), len(columns)))
+			} else {
+				row.Item, row.Errors = csvItem(columns, record)
+			}
+		}
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+}
+
+func csvItem(columns, record []string) (models.CreateItemDTO, models.ValidationErrors) {
+	var dto models.CreateItemDTO
+	var errs models.ValidationErrors
+	for n, column := range columns {
+		value := strings.TrimSpace(record[n])
+		switch column {
+		case "name":
+			dto.Name = value
+		case "currency":
+			dto.Currency = value
+		case "price":
+			if value == "" {
+				errs.Add("price", "price is required")
+				continue
+			}
+			p, err := models.ParseMoney(value)
+			if err != nil {
+				errs.Add("price", "price must be a decimal number")
+				continue
+			}
+			dto.Price = p
+		default:
+			if value == "" {
+				continue
+			}
+			code := strings.ToUpper(strings.TrimPrefix(column, "price_"))
+			p, err := models.ParseMoney(value)
+			if err != nil {
+				errs.Add("prices."+code, "prices."+code+" must be a decimal number")
+				continue
+			}
+			if dto.Prices == nil {
+				dto.Prices = make(map[string]models.Money)
+			}
+			dto.Prices[code] = p
+		}
+	}
+	return dto, errs
+}
+
+// decodeNDJSONRows reads one CreateItemDTO JSON object per line, skipping
+// blank lines.
+func decodeNDJSONRows(body io.Reader, emit func(importRow) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		row := importRow{Line: line}
+		if err := json.Unmarshal(raw, &row.Item); err != nil {
+			row.Malformed = true
+			row.Errors.Add("row", "row is not a valid JSON item")
+		}
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return fmt.Errorf("line %d is longer than 1 MiB", line+1)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+)
+
+// parseItemFilter reads the list filter query parameters, reporting every
+// malformed parameter as models.ValidationErrors.
+func parseItemFilter(q url.Values) (models.ItemFilter, error) {
+	f := models.ItemFilter{
+		Name:       q.Get("name"),
+		NamePrefix: q.Get("name_prefix"),
+		Currency:   models.NormalizeCurrency(q.Get("currency")),
+	}
+
+	var errs models.ValidationErrors
+	if f.Currency != "" && !models.IsCurrency(f.Currency) {
+		errs.Add("currency", "currency must be an ISO 4217 code")
+	}
+	f.MinPrice = parseMoneyParam(q, "min_price", &errs)
+	f.MaxPrice = parseMoneyParam(q, "max_price", &errs)
+	f.CreatedAfter = parseTimeParam(q, "created_after", &errs)
+	f.CreatedBefore = parseTimeParam(q, "created_before", &errs)
+	f.UpdatedAfter = parseTimeParam(q, "updated_after", &errs)
+	f.UpdatedBefore = parseTimeParam(q, "updated_before", &errs)
+	if mode, err := models.ParseDeletedMode(q.Get("deleted")); err != nil {
+		errs.Add("deleted", err.Error())
+	} else {
+		f.Deleted = mode
+	}
+	if len(errs) > 0 {
+		return f, errs
+	}
+
+	return f, f.Validate()
+}
+
+// parseSortParam parses the sort parameter, reporting failures against it.
+func parseSortParam(q url.Values) ([]models.SortField, error) {
+	sort, err := models.ParseSort(q.Get("sort"))
+	if err != nil {
+		return nil, models.ValidationErrors{{Field: "sort", Message: err.Error()}}
+	}
+	return sort, nil
+}
+
+func parseMoneyParam(q url.Values, key string, errs *models.ValidationErrors) *models.Money {
+	raw := q.Get(key)
+	if raw == "" {
+		return nil
+	}
+	v, err := models.ParseMoney(raw)
+	if err != nil {
+		errs.Add(key, key+" must be a number")
+		return nil
+	}
+	return &v
+}
+
+// parseTimeParam accepts RFC 3339 timestamps or plain YYYY-MM-DD dates.
+func parseTimeParam(q url.Values, key string, errs *models.ValidationErrors) *time.Time {
+	raw := q.Get(key)
+	if raw == "" {
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+		return &t
+	}
+	if t, err := time.Parse(time.DateOnly, raw); err == nil {
+		return &t
+	}
+	errs.Add(key, key+" must be an RFC 3339 timestamp or YYYY-MM-DD date")
+	return nil
+}
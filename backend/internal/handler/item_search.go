@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+)
+
+// SearchItems serves GET /api/items/search?q=..., ranking items by how
+// well their name matches q. The list filters narrow the candidates.
+func (h *ItemHandler) SearchItems(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := &models.ItemSearchQuery{
+		Text:   r.URL.Query().Get("q"),
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	var errs models.ValidationErrors
+	if err := query.Validate(); err != nil {
+		errs = append(errs, fieldErrors(err)...)
+	}
+	filter, err := parseItemFilter(r.URL.Query())
+	if err != nil {
+		errs = append(errs, fieldErrors(err)...)
+	}
+	if err := errs.Err(); err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+	query.Filter = filter
+
+	results, err := h.Repo.Search(r.Context(), query)
+	if err != nil {
+		problem.Internal(w, r, err, "Failed to search items")
+		return
+	}
+
+	if results == nil {
+		results = []models.ItemSearchResult{}
+	}
+
+	resp := models.ItemSearchResponse{
+		Query:   query.Text,
+		Results: results,
+		Limit:   limit,
+		Offset:  offset,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/gowthamd/go-crud-app/internal/identity"
+	"github.com/gowthamd/go-crud-app/internal/logging"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+)
+
+// LogHandler reads and changes the minimum level of the running logger.
+type LogHandler struct {
+	Level *slog.LevelVar
+}
+
+func NewLogHandler(level *slog.LevelVar) *LogHandler {
+	return &LogHandler{Level: level}
+}
+
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+func (h *LogHandler) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logLevel{Level: logging.LevelName(h.Level.Level())})
+}
+
+// SetLogLevel changes the level until the next restart, which goes back
+// to LOG_LEVEL.
+func (h *LogHandler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var body logLevel
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	level, err := logging.ParseLevel(body.Level)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "level must be debug, info, warn or error")
+		return
+	}
+
+	previous := h.Level.Level()
+	h.Level.Set(level)
+	slog.WarnContext(r.Context(), "Log level changed",
+		"from", logging.LevelName(previous), "to", logging.LevelName(level), "user", identity.Actor(r.Context()))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logLevel{Level: logging.LevelName(level)})
+}
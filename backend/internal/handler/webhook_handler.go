@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+	"github.com/gowthamd/go-crud-app/internal/repository"
+)
+
+type WebhookHandler struct {
+	Repo repository.WebhookStore
+}
+
+func NewWebhookHandler(repo repository.WebhookStore) *WebhookHandler {
+	return &WebhookHandler{Repo: repo}
+}
+
+// CreateWebhook registers a subscription. The response is the only one
+// that includes the signing secret, unless it is later replaced.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var dto models.CreateWebhookDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := dto.Validate(); err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+
+	hook, err := h.Repo.CreateWebhook(r.Context(), &dto)
+	if err != nil {
+		problem.Internal(w, r, err, "Failed to create webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hook)
+}
+
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.Repo.ListWebhooks(r.Context())
+	if err != nil {
+		problem.Internal(w, r, err, "Failed to list webhooks")
+		return
+	}
+
+	if hooks == nil {
+		hooks = []models.Webhook{}
+	}
+	for n := range hooks {
+		hooks[n].Secret = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hooks)
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+
+	hook, err := h.Repo.GetWebhook(r.Context(), id)
+	if err != nil {
+		writeWebhookError(w, r, err, "Failed to retrieve webhook")
+		return
+	}
+	hook.Secret = ""
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hook)
+}
+
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+
+	var dto models.UpdateWebhookDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := dto.Validate(); err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+
+	hook, err := h.Repo.UpdateWebhook(r.Context(), id, &dto)
+	if err != nil {
+		writeWebhookError(w, r, err, "Failed to update webhook")
+		return
+	}
+	if dto.Secret == nil {
+		hook.Secret = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hook)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Repo.DeleteWebhook(r.Context(), id); err != nil {
+		writeWebhookError(w, r, err, "Failed to delete webhook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries serves the delivery log of a webhook, newest first.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	deliveries, err := h.Repo.Deliveries(r.Context(), id, limit)
+	if err != nil {
+		writeWebhookError(w, r, err, "Failed to list webhook deliveries")
+		return
+	}
+
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// Redeliver queues a delivery to be sent again as a new entry in the log.
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := strconv.ParseInt(chi.URLParam(r, "delivery"), 10, 64)
+	if err != nil || deliveryID < 1 {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid delivery ID")
+		return
+	}
+
+	delivery, err := h.Repo.Redeliver(r.Context(), id, deliveryID)
+	if err != nil {
+		writeWebhookError(w, r, err, "Failed to redeliver webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// uuidParam parses the {id} URL parameter.
+func uuidParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid ID format")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeWebhookError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrWebhookNotFound):
+		problem.Error(w, r, http.StatusNotFound, "Webhook not found")
+	case errors.Is(err, repository.ErrDeliveryNotFound):
+		problem.Error(w, r, http.StatusNotFound, "Delivery not found")
+	default:
+		problem.Internal(w, r, err, fallback)
+	}
+}
@@ -0,0 +1,41 @@
+// Package identity carries the authenticated caller through a request
+// context so lower layers can attribute changes without knowing how the
+// caller was authenticated.
+package identity
+
+import "context"
+
+// Anonymous is the actor recorded for unauthenticated callers.
+const Anonymous = "anonymous"
+
+// Identity describes the caller of a request.
+type Identity struct {
+	// Subject uniquely identifies the caller, e.g. a user id.
+	Subject string
+	// Roles name the caller's roles; the authorization policy maps them to
+	// permissions.
+	Roles []string
+	// Permissions are granted directly, e.g. the scopes of an API key.
+	Permissions []string
+	// Tenant binds the caller to one tenant; empty lets it name any.
+	Tenant string
+}
+
+type contextKey struct{}
+
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(*Identity)
+	return id, ok && id != nil
+}
+
+// Actor names the caller for audit records, falling back to Anonymous.
+func Actor(ctx context.Context) string {
+	if id, ok := FromContext(ctx); ok && id.Subject != "" {
+		return id.Subject
+	}
+	return Anonymous
+}
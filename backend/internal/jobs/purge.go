@@ -0,0 +1,59 @@
+// Package jobs holds background tasks run alongside the API.
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+)
+
+// Purger permanently removes items trashed before a cutoff.
+type Purger interface {
+	Purge(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// PurgeJob periodically hard-deletes items that have been in the trash
+// for longer than Retention. Running it on several replicas is safe.
+type PurgeJob struct {
+	Store     Purger
+	Retention time.Duration
+	Interval  time.Duration
+	now       func() time.Time
+}
+
+func NewPurgeJob(store Purger, retention, interval time.Duration) *PurgeJob {
+	return &PurgeJob{
+		Store:     store,
+		Retention: retention,
+		Interval:  interval,
+		now:       time.Now,
+	}
+}
+
+// RunOnce purges everything trashed before now minus Retention, in every
+// tenant.
+func (j *PurgeJob) RunOnce(ctx context.Context) (int64, error) {
+	return j.Store.Purge(tenant.System(ctx), j.now().Add(-j.Retention))
+}
+
+// Run purges once immediately and then every Interval until ctx is done.
+func (j *PurgeJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := j.RunOnce(ctx); err != nil {
+			slog.Error("Trash purge failed", "error", err)
+		} else if n > 0 {
+			slog.Info("Purged trashed items", "count", n, "retention", j.Retention.String())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
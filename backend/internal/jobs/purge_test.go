@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/repository"
+)
+
+func TestPurgeJob_RunOnce(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryItemRepository()
+
+	trashed, _ := store.Create(ctx, &models.CreateItemDTO{Name: "trashed", Price: models.MustParseMoney("1")})
+	live, _ := store.Create(ctx, &models.CreateItemDTO{Name: "live", Price: models.MustParseMoney("1")})
+	store.Delete(ctx, trashed.ID, 0)
+
+	job := NewPurgeJob(store, time.Hour, time.Minute)
+
+	if n, err := job.RunOnce(ctx); err != nil || n != 0 {
+		t.Fatalf("expected nothing purged within retention, got %d (%v)", n, err)
+	}
+
+	job.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if n, err := job.RunOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("expected 1 purged item after retention, got %d (%v)", n, err)
+	}
+
+	if _, err := store.Restore(ctx, trashed.ID); !errors.Is(err, repository.ErrItemNotFound) {
+		t.Errorf("expected purged item to be gone, got %v", err)
+	}
+	if _, err := store.GetByID(ctx, live.ID); err != nil {
+		t.Errorf("expected live item to survive, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+)
+
+// Webhook request headers. The signature is "sha256=" followed by the hex
+// HMAC-SHA256, keyed with the webhook secret, of the timestamp header, a
+// "." and the raw body.
+const (
+	HeaderWebhookEvent     = "X-Webhook-Event"
+	HeaderWebhookID        = "X-Webhook-Id"
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp"
+	HeaderWebhookSignature = "X-Webhook-Signature"
+)
+
+const (
+	// webhookBatch is how many deliveries one round sends concurrently.
+	webhookBatch = 20
+	// webhookTimeout bounds a single delivery request.
+	webhookTimeout = 10 * time.Second
+	// webhookLease keeps a claimed delivery from other replicas while it is
+	// being sent; it must outlast webhookTimeout.
+	webhookLease = time.Minute
+	// Retries back off exponentially from webhookBackoff up to
+	// webhookMaxBackoff.
+	webhookBackoff    = 30 * time.Second
+	webhookMaxBackoff = 6 * time.Hour
+)
+
+// WebhookQueue hands out due deliveries and records how sending went.
+type WebhookQueue interface {
+	ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.PendingDelivery, error)
+	RecordAttempt(ctx context.Context, id int64, attempt *models.DeliveryAttempt) error
+}
+
+// WebhookDispatcher sends queued webhook deliveries, retrying failures
+// with exponential backoff until MaxAttempts is used up. Running it on
+// several replicas is safe.
+type WebhookDispatcher struct {
+	Store       WebhookQueue
+	Client      *http.Client
+	Interval    time.Duration
+	MaxAttempts int
+	now         func() time.Time
+}
+
+// NewWebhookDispatcher returns a dispatcher whose client only reaches
+// public addresses unless allowPrivate is set; see webhookClient.
+func NewWebhookDispatcher(store WebhookQueue, interval time.Duration, maxAttempts int, allowPrivate bool) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		Store:       store,
+		Client:      webhookClient(allowPrivate),
+		Interval:    interval,
+		MaxAttempts: maxAttempts,
+		now:         time.Now,
+	}
+}
+
+// webhookClient returns the client deliveries are sent with. Redirects
+// are not followed, so a 3xx counts as a failed attempt. Unless
+// allowPrivate is set, connections to loopback, private, link-local and
+// other non-public addresses are refused when dialing, after the host has
+// been resolved, and proxies from the environment are ignored, since the
+// address checked would be the proxy's.
+func webhookClient(allowPrivate bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivate {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: publicOnly}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   webhookTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicOnly is a net.Dialer Control function refusing non-public
+// addresses.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := addrPort.Addr().Unmap(); !isPublic(ip) {
+		return fmt.Errorf("webhook address %s is not public", ip)
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func isPublic(ip netip.Addr) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// RunOnce sends one batch of due deliveries and reports how many were
+// attempted.
+func (d *WebhookDispatcher) RunOnce(ctx context.Context) (int, error) {
+	now := d.now()
+	pending, err := d.Store.ClaimDeliveries(ctx, now, now.Add(webhookLease), webhookBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for n := range pending {
+		wg.Add(1)
+		go func(p *models.PendingDelivery) {
+			defer wg.Done()
+			attempt := d.send(ctx, p)
+			if ctx.Err() != nil {
+				// Shutting down: leave it to be retried when the lease ends.
+				return
+			}
+			if err := d.Store.RecordAttempt(ctx, p.ID, attempt); err != nil {
+				slog.Error("Failed to record webhook delivery", "delivery", p.ID, "error", err)
+			}
+		}(&pending[n])
+	}
+	wg.Wait()
+
+	return len(pending), nil
+}
+
+// Run sends due deliveries every Interval until ctx is done. A full batch
+// is followed by the next one straight away.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := d.RunOnce(ctx)
+		if err != nil {
+			slog.Error("Webhook dispatch failed", "error", err)
+		}
+		if n == webhookBatch && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// send POSTs one delivery and describes the outcome.
+func (d *WebhookDispatcher) send(ctx context.Context, p *models.PendingDelivery) *models.DeliveryAttempt {
+	attempt := &models.DeliveryAttempt{At: d.now()}
+	timestamp := strconv.FormatInt(attempt.At.Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(p.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return d.retry(attempt, p.Attempts+1)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookEvent, p.EventType)
+	req.Header.Set(HeaderWebhookID, p.EventID.String())
+	req.Header.Set(HeaderWebhookTimestamp, timestamp)
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(p.Secret, timestamp, p.Payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return d.retry(attempt, p.Attempts+1)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	attempt.ResponseStatus = &resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		attempt.Delivered = true
+		return attempt
+	}
+	attempt.Error = fmt.Sprintf("endpoint responded %s", resp.Status)
+	return d.retry(attempt, p.Attempts+1)
+}
+
+// retry schedules the next try after a failed attempt, or none once
+// MaxAttempts is reached.
+func (d *WebhookDispatcher) retry(attempt *models.DeliveryAttempt, attempts int) *models.DeliveryAttempt {
+	if attempts >= d.MaxAttempts {
+		return attempt
+	}
+	backoff := webhookMaxBackoff
+	if shift := attempts - 1; shift < 20 {
+		backoff = min(webhookBackoff<<shift, webhookMaxBackoff)
+	}
+	next := attempt.At.Add(backoff)
+	attempt.RetryAt = &next
+	return attempt
+}
+
+// SignWebhook computes the X-Webhook-Signature value for body sent at
+// timestamp.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,160 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/repository"
+)
+
+func TestWebhookDispatcher_DeliversSignedEvents(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryItemRepository()
+
+	var received []models.WebhookEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		want := SignWebhook("0123456789abcdef", r.Header.Get(HeaderWebhookTimestamp), body)
+		if r.Header.Get(HeaderWebhookSignature) != want {
+			t.Errorf("bad signature %q", r.Header.Get(HeaderWebhookSignature))
+		}
+		var e models.WebhookEvent
+		json.Unmarshal(body, &e)
+		if r.Header.Get(HeaderWebhookEvent) != e.Type || r.Header.Get(HeaderWebhookID) != e.ID.String() {
+			t.Errorf("headers do not match event %+v", e)
+		}
+		received = append(received, e)
+	}))
+	defer srv.Close()
+
+	hook, _ := store.CreateWebhook(ctx, &models.CreateWebhookDTO{
+		URL: srv.URL, Events: []string{models.EventItemCreated}, Secret: "0123456789abcdef", Active: ptr(true),
+	})
+	item, _ := store.Create(ctx, &models.CreateItemDTO{Name: "Widget", Price: models.MustParseMoney("1"), Currency: "USD"})
+	store.Delete(ctx, item.ID, 0) // not subscribed
+
+	d := NewWebhookDispatcher(store, time.Minute, 3, true)
+	if n, err := d.RunOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("expected 1 delivery attempted, got %d (%v)", n, err)
+	}
+	if len(received) != 1 || received[0].Type != models.EventItemCreated || received[0].Data.ID != item.ID {
+		t.Fatalf("unexpected events %+v", received)
+	}
+
+	deliveries, _ := store.Deliveries(ctx, hook.ID, 10)
+	if len(deliveries) != 1 || deliveries[0].Status != models.DeliveryDelivered || deliveries[0].Attempts != 1 {
+		t.Errorf("expected a delivered entry in the log, got %+v", deliveries)
+	}
+	if n, _ := d.RunOnce(ctx); n != 0 {
+		t.Errorf("expected nothing left to send, got %d", n)
+	}
+}
+
+func TestWebhookDispatcher_RetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryItemRepository()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	hook, _ := store.CreateWebhook(ctx, &models.CreateWebhookDTO{
+		URL: srv.URL, Events: models.WebhookEventTypes, Secret: "0123456789abcdef", Active: ptr(true),
+	})
+	store.Create(ctx, &models.CreateItemDTO{Name: "Widget", Price: models.MustParseMoney("1"), Currency: "USD"})
+
+	now := time.Now()
+	d := NewWebhookDispatcher(store, time.Minute, 2, true)
+	d.now = func() time.Time { return now }
+
+	d.RunOnce(ctx)
+	deliveries, _ := store.Deliveries(ctx, hook.ID, 10)
+	if got := deliveries[0]; got.Status != models.DeliveryPending || got.NextAttemptAt == nil || !got.NextAttemptAt.Equal(now.Add(webhookBackoff)) {
+		t.Fatalf("expected a retry after %s, got %+v", webhookBackoff, got)
+	}
+	if *deliveries[0].ResponseStatus != http.StatusServiceUnavailable {
+		t.Errorf("expected the response status to be logged, got %d", *deliveries[0].ResponseStatus)
+	}
+
+	if n, _ := d.RunOnce(ctx); n != 0 {
+		t.Fatalf("expected no attempt before the backoff elapsed, got %d", n)
+	}
+
+	now = now.Add(webhookBackoff)
+	d.RunOnce(ctx)
+	deliveries, _ = store.Deliveries(ctx, hook.ID, 10)
+	if got := deliveries[0]; got.Status != models.DeliveryFailed || got.Attempts != 2 || got.NextAttemptAt != nil {
+		t.Fatalf("expected the delivery to fail after 2 attempts, got %+v", got)
+	}
+
+	redelivery, err := store.Redeliver(ctx, hook.ID, deliveries[0].ID)
+	if err != nil || redelivery.Status != models.DeliveryPending || redelivery.EventID != deliveries[0].EventID {
+		t.Fatalf("unexpected redelivery %+v (%v)", redelivery, err)
+	}
+}
+
+func TestWebhookDispatcher_RefusesPrivateTargets(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryItemRepository()
+	var hits int
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits++ }))
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+
+	store.CreateWebhook(ctx, &models.CreateWebhookDTO{
+		URL: target.URL, Events: models.WebhookEventTypes, Secret: "0123456789abcdef", Active: ptr(true),
+	})
+	redirected, _ := store.CreateWebhook(ctx, &models.CreateWebhookDTO{
+		URL: redirect.URL, Events: models.WebhookEventTypes, Secret: "0123456789abcdef", Active: ptr(true),
+	})
+	store.Create(ctx, &models.CreateItemDTO{Name: "Widget", Price: models.MustParseMoney("1"), Currency: "USD"})
+
+	// Both test servers listen on loopback.
+	if n, _ := NewWebhookDispatcher(store, time.Minute, 1, false).RunOnce(ctx); n != 2 || hits != 0 {
+		t.Fatalf("expected 2 refused deliveries, got %d attempted and %d received", n, hits)
+	}
+
+	deliveries, _ := store.Deliveries(ctx, redirected.ID, 10)
+	if len(deliveries) != 1 || !strings.Contains(deliveries[0].LastError, "not public") {
+		t.Fatalf("expected the refusal to be logged, got %+v", deliveries)
+	}
+	store.Redeliver(ctx, redirected.ID, deliveries[0].ID)
+	NewWebhookDispatcher(store, time.Minute, 1, true).RunOnce(ctx)
+	deliveries, _ = store.Deliveries(ctx, redirected.ID, 10)
+	if hits != 0 || deliveries[0].ResponseStatus == nil || *deliveries[0].ResponseStatus != http.StatusFound {
+		t.Errorf("expected the redirect to fail the delivery without being followed, got %d hits and %+v", hits, deliveries[0])
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+	}
+	for addr, want := range tests {
+		if got := isPublic(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("isPublic(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
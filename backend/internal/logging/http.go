@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type entryKey struct{}
+
+// entry collects the attributes added while a request is served.
+type entry struct {
+	mu    sync.Mutex
+	attrs []any
+}
+
+// AddAttrs adds key/value pairs to the request log line of ctx's request,
+// for facts only known deeper in the handler chain such as the caller.
+// It does nothing outside Middleware.
+func AddAttrs(ctx context.Context, args ...any) {
+	if e, ok := ctx.Value(entryKey{}).(*entry); ok {
+		e.mu.Lock()
+		e.attrs = append(e.attrs, args...)
+		e.mu.Unlock()
+	}
+}
+
+// Middleware logs one line per request once it is served, at error level
+// for server errors. Only the path is logged: query strings may carry
+// tokens.
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			e := &entry{}
+			ctx := context.WithValue(r.Context(), entryKey{}, e)
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			route := ""
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			e.mu.Lock()
+			args := append([]any{
+				"method", r.Method,
+				"path", r.URL.Path,
+				"route", route,
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+				"remote", r.RemoteAddr,
+			}, e.attrs...)
+			e.mu.Unlock()
+			logger.Log(ctx, level, "request", args...)
+		})
+	}
+}
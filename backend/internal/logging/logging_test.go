@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// decode parses the JSON lines written to buf.
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line map[string]any
+		if err := dec.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	logger := New(&buf, level)
+
+	logger.Debug("hidden")
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "host/abc-000001")
+	logger.With("api_key", "k").ErrorContext(ctx, "failed",
+		"error", errors.New("boom"), "Password", "hunter2", "user", "alice",
+		slog.Group("req", "authorization", "Bearer x", "access_token", "y"))
+	level.Set(slog.LevelDebug)
+	logger.Debug("shown")
+
+	lines := decode(t, &buf)
+	if len(lines) != 2 || lines[1]["msg"] != "shown" {
+		t.Fatalf("expected debug to be filtered until enabled, got %v", lines)
+	}
+	got := lines[0]
+	if got["level"] != "ERROR" || got["error"] != "boom" || got["user"] != "alice" || got["request_id"] != "host/abc-000001" {
+		t.Errorf("got %v", got)
+	}
+	req, _ := got["req"].(map[string]any)
+	for _, v := range []any{got["api_key"], got["Password"], req["authorization"], req["access_token"]} {
+		if v != Redacted {
+			t.Errorf("expected sensitive values to be redacted, got %v", got)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if l, err := ParseLevel("WARN"); err != nil || l != slog.LevelWarn || LevelName(l) != "warn" {
+		t.Fatalf("got %v, %v", l, err)
+	}
+	for _, s := range []string{"", "verbose", "info+2"} {
+		if _, err := ParseLevel(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(Middleware(New(&buf, slog.LevelInfo)))
+	r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		AddAttrs(r.Context(), "user", "alice")
+		w.Write([]byte("ok"))
+	})
+	r.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42?access_token=secret", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	lines := decode(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	got := lines[0]
+	if got["level"] != "INFO" || got["msg"] != "request" || got["method"] != "GET" || got["path"] != "/items/42" ||
+		got["route"] != "/items/{id}" || got["status"] != 200.0 || got["bytes"] != 2.0 || got["user"] != "alice" {
+		t.Errorf("got %v", got)
+	}
+	if _, ok := got["duration_ms"].(float64); !ok || got["request_id"] == nil {
+		t.Errorf("expected a duration and request id, got %v", got)
+	}
+	if lines[1]["level"] != "ERROR" || lines[1]["status"] != 500.0 {
+		t.Errorf("expected a server error to be logged as an error, got %v", lines[1])
+	}
+}
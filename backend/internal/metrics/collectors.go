@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolStater reports connection pool statistics; db.DB satisfies it.
+type PoolStater interface {
+	Stat() *pgxpool.Stat
+}
+
+var (
+	poolAcquired = prometheus.NewDesc("db_pool_acquired_connections", "Connections currently in use.", nil, nil)
+	poolIdle     = prometheus.NewDesc("db_pool_idle_connections", "Idle connections in the pool.", nil, nil)
+	poolTotal    = prometheus.NewDesc("db_pool_total_connections", "Open connections, including those being established.", nil, nil)
+	poolMax      = prometheus.NewDesc("db_pool_max_connections", "Maximum size of the pool.", nil, nil)
+	poolAcquires = prometheus.NewDesc("db_pool_acquires_total", "Connections acquired from the pool.", nil, nil)
+	poolWaits    = prometheus.NewDesc("db_pool_acquire_waits_total", "Acquires that had to wait for a connection.", nil, nil)
+	poolWait     = prometheus.NewDesc("db_pool_acquire_wait_seconds_total", "Time spent acquiring connections.", nil, nil)
+	poolCanceled = prometheus.NewDesc("db_pool_canceled_acquires_total", "Acquires canceled by their context.", nil, nil)
+)
+
+// PoolCollector reads the pool statistics at every scrape.
+type PoolCollector struct {
+	Pool PoolStater
+}
+
+func NewPoolCollector(pool PoolStater) *PoolCollector {
+	return &PoolCollector{Pool: pool}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{poolAcquired, poolIdle, poolTotal, poolMax, poolAcquires, poolWaits, poolWait, poolCanceled} {
+		ch <- d
+	}
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.Pool.Stat()
+	ch <- prometheus.MustNewConstMetric(poolAcquired, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdle, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotal, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMax, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolWaits, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolWait, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(poolCanceled, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
+
+// ItemStatser computes item statistics; every ItemStore satisfies it.
+type ItemStatser interface {
+	ItemStats(ctx context.Context) (*models.ItemStats, error)
+}
+
+var (
+	itemsLive    = prometheus.NewDesc("items_live", "Items not in the trash, across all tenants.", nil, nil)
+	itemsTrashed = prometheus.NewDesc("items_trashed", "Items in the trash, across all tenants.", nil, nil)
+	itemsCreated = prometheus.NewDesc("items_created_last_minute", "Items created during the last minute.", nil, nil)
+	itemsDeleted = prometheus.NewDesc("items_deleted_last_minute", "Items moved to the trash during the last minute.", nil, nil)
+)
+
+// itemStatsTimeout bounds the query run at every scrape.
+const itemStatsTimeout = 5 * time.Second
+
+// ItemCollector reads the item statistics at every scrape. They come
+// from the store, so every replica reports the same catalog-wide values.
+type ItemCollector struct {
+	Store ItemStatser
+}
+
+func NewItemCollector(store ItemStatser) *ItemCollector {
+	return &ItemCollector{Store: store}
+}
+
+func (c *ItemCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{itemsLive, itemsTrashed, itemsCreated, itemsDeleted} {
+		ch <- d
+	}
+}
+
+func (c *ItemCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(tenant.System(context.Background()), itemStatsTimeout)
+	defer cancel()
+
+	s, err := c.Store.ItemStats(ctx)
+	if err != nil {
+		slog.Error("Failed to collect item metrics", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(itemsLive, prometheus.GaugeValue, float64(s.Live))
+	ch <- prometheus.MustNewConstMetric(itemsTrashed, prometheus.GaugeValue, float64(s.Trashed))
+	ch <- prometheus.MustNewConstMetric(itemsCreated, prometheus.GaugeValue, float64(s.CreatedLastMinute))
+	ch <- prometheus.MustNewConstMetric(itemsDeleted, prometheus.GaugeValue, float64(s.DeletedLastMinute))
+}
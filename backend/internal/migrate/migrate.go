@@ -0,0 +1,311 @@
+// Package migrate applies the versioned SQL schema migrations and records
+// them in the schema_migrations table.
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// lockKey identifies the advisory lock held while migrating, so that
+// replicas starting together or a job racing a rollout migrate in turn.
+const lockKey int64 = 0x6d69677261746531
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down|check)\.sql$`)
+
+// Migration is one schema change and the statements undoing it. Check,
+// when present, is a query returning true if the schema already contains
+// the change, which Baseline relies on.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	Check   string
+}
+
+// Load reads the migrations in the root of fsys, sorted by version. Every
+// version needs both an up and a down file; the check file is optional.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %s: version must be a positive integer", e.Name())
+		}
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, mig.Name, m[2])
+		}
+		switch m[3] {
+		case "up":
+			mig.Up = string(data)
+		case "down":
+			mig.Down = string(data)
+		case "check":
+			mig.Check = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	return migrations, nil
+}
+
+// Status reports whether a migration has been applied, and when.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator runs migrations over a single connection, holding an advisory
+// lock for the duration of every command. Each migration runs in its own
+// transaction together with its schema_migrations record, so a failed one
+// leaves nothing behind.
+type Migrator struct {
+	Conn       *pgx.Conn
+	Migrations []Migration
+}
+
+func New(conn *pgx.Conn, migrations []Migration) *Migrator {
+	return &Migrator{Conn: conn, Migrations: migrations}
+}
+
+// Latest is the version of the newest migration, or 0 without any.
+func (m *Migrator) Latest() int64 {
+	if len(m.Migrations) == 0 {
+		return 0
+	}
+	return m.Migrations[len(m.Migrations)-1].Version
+}
+
+// Up applies every pending migration and returns how many ran.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rolls back the most recently applied migration, if any.
+func (m *Migrator) Down(ctx context.Context) (int, error) {
+	n := 0
+	err := m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		var last *Migration
+		for i := range m.Migrations {
+			if _, ok := applied[m.Migrations[i].Version]; ok {
+				last = &m.Migrations[i]
+			}
+		}
+		if last == nil {
+			return nil
+		}
+		n = 1
+		return m.rollback(ctx, last)
+	})
+	return n, err
+}
+
+// To migrates up or down so that exactly the migrations up to version
+// are applied; version 0 rolls everything back.
+func (m *Migrator) To(ctx context.Context, version int64) (int, error) {
+	if version != 0 && !slices.ContainsFunc(m.Migrations, func(mig Migration) bool { return mig.Version == version }) {
+		return 0, fmt.Errorf("unknown migration version %d", version)
+	}
+	n := 0
+	err := m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		up, down := plan(m.Migrations, applied, version)
+		for _, mig := range down {
+			if err := m.rollback(ctx, mig); err != nil {
+				return err
+			}
+			n++
+		}
+		for _, mig := range up {
+			if err := m.apply(ctx, mig); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Baseline records the migrations up to version as applied without
+// running them, for databases migrated before schema_migrations existed.
+// It refuses to touch a database that already records migrations, and to
+// record a migration whose check finds it missing from the schema or
+// that has no check.
+func (m *Migrator) Baseline(ctx context.Context, version int64) error {
+	return m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) > 0 {
+			return errors.New("schema_migrations is not empty")
+		}
+		for _, mig := range m.Migrations {
+			if mig.Version > version {
+				break
+			}
+			if mig.Check == "" {
+				return fmt.Errorf("migration %d_%s has no check; cannot verify it is in the schema", mig.Version, mig.Name)
+			}
+			var present bool
+			if err := m.Conn.QueryRow(ctx, mig.Check).Scan(&present); err != nil {
+				return fmt.Errorf("migration %d_%s check: %w", mig.Version, mig.Name, err)
+			}
+			if !present {
+				return fmt.Errorf("migration %d_%s is not in the schema; baseline at most %d and run migrate up", mig.Version, mig.Name, mig.Version-1)
+			}
+		}
+		for _, mig := range m.Migrations {
+			if mig.Version > version {
+				break
+			}
+			if _, err := m.Conn.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Status lists every known migration and when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.Migrations {
+			s := Status{Migration: mig}
+			if at, ok := applied[mig.Version]; ok {
+				s.AppliedAt = &at
+			}
+			statuses = append(statuses, s)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// plan returns the migrations to apply, oldest first, and to roll back,
+// newest first, so that exactly those up to target are applied.
+func plan(migrations []Migration, applied map[int64]time.Time, target int64) (up, down []*Migration) {
+	for i := range migrations {
+		mig := &migrations[i]
+		_, done := applied[mig.Version]
+		switch {
+		case mig.Version <= target && !done:
+			up = append(up, mig)
+		case mig.Version > target && done:
+			down = append(down, mig)
+		}
+	}
+	slices.Reverse(down)
+	return up, down
+}
+
+func (m *Migrator) locked(ctx context.Context, fn func() error) error {
+	if _, err := m.Conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer m.Conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	if _, err := m.Conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+		)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return fn()
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	rows, err := m.Conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) apply(ctx context.Context, mig *Migration) error {
+	return m.run(ctx, mig, "up", mig.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name)
+}
+
+func (m *Migrator) rollback(ctx context.Context, mig *Migration) error {
+	return m.run(ctx, mig, "down", mig.Down, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+}
+
+// run executes the statements of one migration and updates its record in
+// a single transaction.
+func (m *Migrator) run(ctx context.Context, mig *Migration, direction, statements, record string, args ...any) error {
+	start := time.Now()
+	err := pgx.BeginFunc(ctx, m.Conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, statements); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, record, args...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s %s: %w", mig.Version, mig.Name, direction, err)
+	}
+	slog.Info("Migrated", "version", mig.Version, "name", mig.Name, "direction", direction,
+		"duration_ms", float64(time.Since(start).Microseconds())/1000)
+	return nil
+}
@@ -0,0 +1,112 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000002_add_b.up.sql":       {Data: []byte("ALTER TABLE a ADD b INT;")},
+		"000002_add_b.down.sql":     {Data: []byte("ALTER TABLE a DROP b;")},
+		"000001_create_a.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"000001_create_a.down.sql":  {Data: []byte("DROP TABLE a;")},
+		"000001_create_a.check.sql": {Data: []byte("SELECT to_regclass('a') IS NOT NULL;")},
+		"README.md":                 {Data: []byte("ignored")},
+	}
+	got, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Version != 1 || got[0].Name != "create_a" || got[0].Down != "DROP TABLE a;" || got[1].Version != 2 {
+		t.Fatalf("got %+v", got)
+	}
+	if got[0].Check == "" || got[1].Check != "" {
+		t.Errorf("expected only the first migration to have a check, got %+v", got)
+	}
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"missing down": {"000001_a.up.sql": {Data: []byte("SELECT 1;")}},
+		"renamed": {
+			"000001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"000001_b.down.sql": {Data: []byte("SELECT 1;")},
+		},
+		"version zero": {
+			"0_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"0_a.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	} {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoad_Embedded(t *testing.T) {
+	got, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, mig := range got {
+		if mig.Version != int64(i+1) {
+			t.Fatalf("expected versions without gaps, got %d at position %d", mig.Version, i)
+		}
+		if mig.Check == "" {
+			t.Errorf("migration %d_%s has no check file, so it cannot be baselined", mig.Version, mig.Name)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	migs := []Migration{{Version: 1}, {Version: 2}, {Version: 3}, {Version: 4}}
+	at := time.Now()
+	versions := func(ms []*Migration) []int64 {
+		var v []int64
+		for _, m := range ms {
+			v = append(v, m.Version)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name     string
+		applied  []int64
+		target   int64
+		up, down []int64
+	}{
+		{"fresh", nil, 4, []int64{1, 2, 3, 4}, nil},
+		{"pending", []int64{1, 2}, 4, []int64{3, 4}, nil},
+		{"current", []int64{1, 2, 3, 4}, 4, nil, nil},
+		{"down to 2", []int64{1, 2, 3, 4}, 2, nil, []int64{4, 3}},
+		{"everything", []int64{1, 2, 3}, 0, nil, []int64{3, 2, 1}},
+		{"gap", []int64{1, 3}, 4, []int64{2, 4}, nil},
+	}
+	for _, tt := range tests {
+		applied := make(map[int64]time.Time)
+		for _, v := range tt.applied {
+			applied[v] = at
+		}
+		up, down := plan(migs, applied, tt.target)
+		if got := versions(up); !equal(got, tt.up) {
+			t.Errorf("%s: expected to apply %v, got %v", tt.name, tt.up, got)
+		}
+		if got := versions(down); !equal(got, tt.down) {
+			t.Errorf("%s: expected to roll back %v, got %v", tt.name, tt.down, got)
+		}
+	}
+}
+
+func equal(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
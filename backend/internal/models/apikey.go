@@ -0,0 +1,74 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey authenticates a machine client. Only a hash of the key is kept;
+// Prefix is its public, unique beginning, shown to tell keys apart.
+// Scopes are the permissions the key grants, within the tenant it was
+// created in.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	Tenant     string     `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Hash       []byte     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Expired reports whether the key can no longer be used at now.
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// CreatedAPIKey is the response to creating a key, the only one that
+// includes the key itself.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type CreateAPIKeyDTO struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Validate checks the DTO against the scopes a key may be granted and
+// de-duplicates its scopes.
+func (d *CreateAPIKeyDTO) Validate(scopes []string) error {
+	var errs ValidationErrors
+	if d.Name == "" {
+		errs.Add("name", "name is required")
+	} else if len(d.Name) > 100 {
+		errs.Add("name", "name must be at most 100 characters")
+	}
+
+	if len(d.Scopes) == 0 {
+		errs.Add("scopes", "at least one scope is required")
+	}
+	var out []string
+	for _, s := range d.Scopes {
+		if !slices.Contains(scopes, s) {
+			errs.Add("scopes", "unknown scope "+s)
+			continue
+		}
+		if !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	d.Scopes = out
+
+	if d.ExpiresAt != nil && !d.ExpiresAt.After(time.Now()) {
+		errs.Add("expires_at", "expires_at must be in the future")
+	}
+	return errs.Err()
+}
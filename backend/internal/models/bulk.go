@@ -0,0 +1,144 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Bulk operation kinds.
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// Bulk execution modes. Atomic runs every operation in one transaction and
+// applies none of them if any fails; best effort applies what it can and
+// reports each outcome.
+const (
+	BulkAtomic     = "atomic"
+	BulkBestEffort = "best_effort"
+)
+
+// MaxBulkOperations caps the number of operations in one bulk request.
+const MaxBulkOperations = 1000
+
+// BulkRequest is the body of POST /api/items/bulk.
+type BulkRequest struct {
+	Mode       string          `json:"mode"`
+	Operations []BulkOperation `json:"operations"`
+}
+
+// Validate checks the request envelope, defaulting Mode to atomic.
+// Individual operations are checked by BulkOperation.Validate.
+func (b *BulkRequest) Validate() error {
+	var errs ValidationErrors
+	switch b.Mode {
+	case "":
+		b.Mode = BulkAtomic
+	case BulkAtomic, BulkBestEffort:
+	default:
+		errs.Add("mode", "mode must be atomic or best_effort")
+	}
+	if len(b.Operations) == 0 {
+		errs.Add("operations", "operations must not be empty")
+	} else if len(b.Operations) > MaxBulkOperations {
+		errs.Add("operations", fmt.Sprintf("operations must not contain more than %d entries", MaxBulkOperations))
+	}
+	return errs.Err()
+}
+
+// BulkOperation is one create, update or delete in a bulk request. Item
+// holds a CreateItemDTO or UpdateItemDTO depending on Op and is decoded by
+// Validate. Version makes an update or delete conditional, like If-Match.
+type BulkOperation struct {
+	Op      string          `json:"op"`
+	ID      uuid.UUID       `json:"id"`
+	Version int64           `json:"version,omitempty"`
+	Item    json.RawMessage `json:"item,omitempty"`
+
+	Create *CreateItemDTO `json:"-"`
+	Update *UpdateItemDTO `json:"-"`
+}
+
+// Validate decodes and validates the operation's payload, filling Create
+// or Update.
+func (o *BulkOperation) Validate() error {
+	var errs ValidationErrors
+	switch o.Op {
+	case BulkCreate:
+		var dto CreateItemDTO
+		if !o.decodeItem(&dto, &errs) {
+			return errs
+		}
+		if err := dto.Validate(); err != nil {
+			return mergeValidation(errs, err)
+		}
+		o.Create = &dto
+	case BulkUpdate, BulkDelete:
+		if o.ID == uuid.Nil {
+			errs.Add("id", "id is required")
+		}
+		if o.Version < 0 {
+			errs.Add("version", "version must be positive")
+		}
+		if o.Op == BulkDelete {
+			break
+		}
+		var dto UpdateItemDTO
+		if !o.decodeItem(&dto, &errs) {
+			return errs
+		}
+		if err := dto.Validate(); err != nil {
+			return mergeValidation(errs, err)
+		}
+		o.Update = &dto
+	default:
+		errs.Add("op", "op must be create, update or delete")
+	}
+	return errs.Err()
+}
+
+func (o *BulkOperation) decodeItem(dst any, errs *ValidationErrors) bool {
+	if len(o.Item) == 0 || bytes.Equal(o.Item, []byte("null")) {
+		errs.Add("item", "item is required")
+		return false
+	}
+	if err := json.Unmarshal(o.Item, dst); err != nil {
+		errs.Add("item", "item is not a valid item payload")
+		return false
+	}
+	return true
+}
+
+func mergeValidation(errs ValidationErrors, err error) error {
+	var fields ValidationErrors
+	if !errors.As(err, &fields) {
+		return err
+	}
+	return append(errs, fields...)
+}
+
+// BulkResult reports the outcome of one operation using the status code a
+// single-item request would have returned.
+type BulkResult struct {
+	Index  int          `json:"index"`
+	Op     string       `json:"op"`
+	Status int          `json:"status"`
+	ID     *uuid.UUID   `json:"id,omitempty"`
+	Item   *Item        `json:"item,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// BulkResponse is the body returned by POST /api/items/bulk.
+type BulkResponse struct {
+	Mode      string       `json:"mode"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []BulkResult `json:"results"`
+}
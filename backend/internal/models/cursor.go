@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cursor marks a position in the keyset listing order (created_at DESC,
+// id DESC). Backward cursors page towards newer items.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+	Backward  bool
+}
+
+type cursorPayload struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"i"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+// Encode returns the opaque, URL-safe form of the cursor.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(cursorPayload{CreatedAt: c.CreatedAt, ID: c.ID, Backward: c.Backward})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor produced by Encode.
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var p cursorPayload
+	if err := json.Unmarshal(b, &p); err != nil || p.ID == uuid.Nil || p.CreatedAt.IsZero() {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &Cursor{CreatedAt: p.CreatedAt, ID: p.ID, Backward: p.Backward}, nil
+}
+
+// CursorPage is the response body of a keyset-paginated listing.
+type CursorPage struct {
+	Items      []Item `json:"items"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// ItemEvent is one entry of the item change feed: a revision together with
+// its position in the feed. Clients resume the feed by quoting ID in
+// Last-Event-ID.
+// Tenant is the tenant the item belongs to; subscribers only see events
+// of their own tenant.
+type ItemEvent struct {
+	ID     int64  `json:"id"`
+	Tenant string `json:"-"`
+	ItemRevision
+}
@@ -0,0 +1,21 @@
+package models
+
+// MaxImportRows caps the number of data rows accepted by one import.
+const MaxImportRows = 10000
+
+// ImportRowError lists why one row of an import was rejected. Row is the
+// 1-based line number in the uploaded file.
+type ImportRowError struct {
+	Row    int          `json:"row"`
+	Errors []FieldError `json:"errors"`
+}
+
+// ImportResult is the body returned by POST /api/items/import. On a dry
+// run Imported is the number of rows that would have been written.
+type ImportResult struct {
+	DryRun   bool             `json:"dry_run"`
+	Rows     int              `json:"rows"`
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []ImportRowError `json:"errors,omitempty"`
+}
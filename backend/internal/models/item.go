@@ -1,7 +1,7 @@
 package models
 
 import (
-	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -9,60 +9,154 @@ import (
 )
 
 type Item struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Price    Money     `json:"price"`
+	Currency string    `json:"currency"`
+	// Prices holds optional prices in other currencies, keyed by ISO 4217 code.
+	Prices    map[string]Money `json:"prices,omitempty"`
+	Version   int64            `json:"version"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+	// DeletedAt is set while the item sits in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 type CreateItemDTO struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+	Name     string           `json:"name"`
+	Price    Money            `json:"price"`
+	Currency string           `json:"currency,omitempty"`
+	Prices   map[string]Money `json:"prices,omitempty"`
 }
 
+// Validate normalises the DTO and reports every invalid field at once as
+// ValidationErrors. An empty currency becomes DefaultCurrency().
 func (d *CreateItemDTO) Validate() error {
+	var errs ValidationErrors
 	d.Name = strings.TrimSpace(d.Name)
 	if d.Name == "" {
-		return fmt.Errorf("name is required")
+		errs.Add("name", "name is required")
+	} else if len(d.Name) > 255 {
+		errs.Add("name", "name must be 255 characters or less")
 	}
-	if len(d.Name) > 255 {
-		return fmt.Errorf("name must be 255 characters or less")
-	}
-	if d.Price < 0 {
-		return fmt.Errorf("price must be non-negative")
-	}
-	if d.Price > 99999999.99 {
-		return fmt.Errorf("price exceeds maximum allowed value")
+	validatePrice("price", d.Price, &errs)
+
+	d.Currency = NormalizeCurrency(d.Currency)
+	if d.Currency == "" {
+		d.Currency = DefaultCurrency()
 	}
-	return nil
+	validateCurrency(d.Currency, &errs)
+	d.Prices = validatePrices(d.Prices, d.Currency, &errs)
+	return errs.Err()
 }
 
 type UpdateItemDTO struct {
-	Name  *string  `json:"name,omitempty"`
-	Price *float64 `json:"price,omitempty"`
+	Name     *string `json:"name,omitempty"`
+	Price    *Money  `json:"price,omitempty"`
+	Currency *string `json:"currency,omitempty"`
+	// Prices replaces the whole price list when present; {} clears it.
+	Prices *map[string]Money `json:"prices,omitempty"`
 }
 
+// Validate normalises the DTO and reports every invalid field at once as
+// ValidationErrors.
 func (d *UpdateItemDTO) Validate() error {
+	var errs ValidationErrors
 	if d.Name != nil {
 		trimmed := strings.TrimSpace(*d.Name)
 		d.Name = &trimmed
 		if trimmed == "" {
-			return fmt.Errorf("name cannot be empty")
-		}
-		if len(trimmed) > 255 {
-			return fmt.Errorf("name must be 255 characters or less")
+			errs.Add("name", "name cannot be empty")
+		} else if len(trimmed) > 255 {
+			errs.Add("name", "name must be 255 characters or less")
 		}
 	}
 	if d.Price != nil {
-		if *d.Price < 0 {
-			return fmt.Errorf("price must be non-negative")
+		validatePrice("price", *d.Price, &errs)
+	}
+	var currency string
+	if d.Currency != nil {
+		currency = NormalizeCurrency(*d.Currency)
+		d.Currency = &currency
+		validateCurrency(currency, &errs)
+	}
+	if d.Prices != nil {
+		prices := validatePrices(*d.Prices, currency, &errs)
+		if prices == nil {
+			prices = map[string]Money{}
+		}
+		d.Prices = &prices
+	}
+	return errs.Err()
+}
+
+// CheckPrices rejects a new price list repeating current, the currency
+// the item is stored in, when the update keeps that currency. Validate
+// cannot tell without the stored item, so stores call this once they
+// have it.
+func (d *UpdateItemDTO) CheckPrices(current string) error {
+	if d.Prices == nil || d.Currency != nil {
+		return nil
+	}
+	var errs ValidationErrors
+	if _, ok := (*d.Prices)[current]; ok {
+		field := "prices." + current
+		errs.Add(field, field+" duplicates the item currency; use price instead")
+	}
+	return errs.Err()
+}
+
+func validatePrice(field string, p Money, errs *ValidationErrors) {
+	if p.IsNegative() {
+		errs.Add(field, field+" must be non-negative")
+	}
+	if p.Cmp(MaxPrice) > 0 {
+		errs.Add(field, field+" exceeds maximum allowed value")
+	}
+	if !p.HasValidScale() {
+		errs.Add(field, field+" must have at most two decimal places")
+	}
+}
+
+func validateCurrency(code string, errs *ValidationErrors) {
+	if !IsCurrency(code) {
+		errs.Add("currency", "currency must be an ISO 4217 code")
+	}
+}
+
+// validatePrices normalises the keys of a price list and validates each
+// entry. base is the item currency, which must not be repeated in the list.
+func validatePrices(prices map[string]Money, base string, errs *ValidationErrors) map[string]Money {
+	if len(prices) == 0 {
+		return nil
+	}
+
+	codes := make([]string, 0, len(prices))
+	for code := range prices {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes) // report errors in a stable order
+
+	out := make(map[string]Money, len(prices))
+	for _, raw := range codes {
+		code := NormalizeCurrency(raw)
+		field := "prices." + raw
+		switch {
+		case !IsCurrency(code):
+			errs.Add(field, field+" is not an ISO 4217 currency code")
+			continue
+		case code == base:
+			errs.Add(field, field+" duplicates the item currency; use price instead")
+			continue
 		}
-		if *d.Price > 99999999.99 {
-			return fmt.Errorf("price exceeds maximum allowed value")
+		if _, dup := out[code]; dup {
+			errs.Add(field, field+" is listed more than once")
+			continue
 		}
+		validatePrice(field, prices[raw], errs)
+		out[code] = prices[raw]
 	}
-	return nil
+	return out
 }
 
 type PaginatedResponse struct {
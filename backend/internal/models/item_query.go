@@ -0,0 +1,109 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SortableColumns whitelists the item columns a listing may be ordered by.
+var SortableColumns = map[string]bool{
+	"name":       true,
+	"price":      true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// ParseSort parses a `sort=price,-name` style expression. A leading "-"
+// sorts that column in descending order.
+func ParseSort(expr string) ([]SortField, error) {
+	var fields []SortField
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := SortField{Column: part}
+		if strings.HasPrefix(part, "-") {
+			field = SortField{Column: part[1:], Desc: true}
+		}
+		if !SortableColumns[field.Column] {
+			return nil, fmt.Errorf("cannot sort by %q", field.Column)
+		}
+		if seen[field.Column] {
+			return nil, fmt.Errorf("duplicate sort column %q", field.Column)
+		}
+		seen[field.Column] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// DeletedMode selects whether trashed items appear in a listing.
+type DeletedMode int
+
+const (
+	DeletedExclude DeletedMode = iota
+	DeletedInclude
+	DeletedOnly
+)
+
+// ParseDeletedMode maps the `deleted` query parameter values.
+func ParseDeletedMode(s string) (DeletedMode, error) {
+	switch s {
+	case "", "exclude":
+		return DeletedExclude, nil
+	case "include":
+		return DeletedInclude, nil
+	case "only":
+		return DeletedOnly, nil
+	}
+	return 0, fmt.Errorf("deleted must be one of exclude, include or only")
+}
+
+// ItemFilter narrows an item listing. Zero values mean "no constraint".
+// Lower time bounds are inclusive and upper bounds exclusive.
+type ItemFilter struct {
+	Name       string
+	NamePrefix string
+	// Currency matches items priced in that currency, either as their base
+	// currency or through their price list. Price bounds always apply to
+	// the base price.
+	Currency      string
+	MinPrice      *Money
+	MaxPrice      *Money
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Deleted       DeletedMode
+}
+
+func (f *ItemFilter) Validate() error {
+	var errs ValidationErrors
+	if f.MinPrice != nil && f.MaxPrice != nil && f.MinPrice.Cmp(*f.MaxPrice) > 0 {
+		errs.Add("min_price", "min_price must not exceed max_price")
+	}
+	if f.CreatedAfter != nil && f.CreatedBefore != nil && f.CreatedAfter.After(*f.CreatedBefore) {
+		errs.Add("created_after", "created_after must not be later than created_before")
+	}
+	if f.UpdatedAfter != nil && f.UpdatedBefore != nil && f.UpdatedAfter.After(*f.UpdatedBefore) {
+		errs.Add("updated_after", "updated_after must not be later than updated_before")
+	}
+	return errs.Err()
+}
+
+// ItemListQuery describes one page of a filtered, sorted item listing.
+// An empty Sort means newest first.
+type ItemListQuery struct {
+	Filter ItemFilter
+	Sort   []SortField
+	Limit  int
+	Offset int
+}
@@ -1,8 +1,13 @@
 package models
 
 import (
+	"errors"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
 func TestCreateItemDTO_Validate(t *testing.T) {
@@ -13,39 +18,49 @@ func TestCreateItemDTO_Validate(t *testing.T) {
 	}{
 		{
 			name:    "valid item",
-			dto:     CreateItemDTO{Name: "Widget", Price: 9.99},
+			dto:     CreateItemDTO{Name: "Widget", Price: MustParseMoney("9.99")},
 			wantErr: "",
 		},
 		{
 			name:    "empty name",
-			dto:     CreateItemDTO{Name: "", Price: 9.99},
+			dto:     CreateItemDTO{Name: "", Price: MustParseMoney("9.99")},
 			wantErr: "name is required",
 		},
 		{
 			name:    "whitespace-only name",
-			dto:     CreateItemDTO{Name: "   ", Price: 9.99},
+			dto:     CreateItemDTO{Name: "   ", Price: MustParseMoney("9.99")},
 			wantErr: "name is required",
 		},
 		{
 			name:    "name too long",
-			dto:     CreateItemDTO{Name: strings.Repeat("a", 256), Price: 9.99},
+			dto:     CreateItemDTO{Name: strings.Repeat("a", 256), Price: MustParseMoney("9.99")},
 			wantErr: "name must be 255 characters or less",
 		},
 		{
 			name:    "negative price",
-			dto:     CreateItemDTO{Name: "Widget", Price: -1.0},
+			dto:     CreateItemDTO{Name: "Widget", Price: MustParseMoney("-1.0")},
 			wantErr: "price must be non-negative",
 		},
 		{
 			name:    "zero price is valid",
-			dto:     CreateItemDTO{Name: "Free Item", Price: 0},
+			dto:     CreateItemDTO{Name: "Free Item", Price: MustParseMoney("0")},
 			wantErr: "",
 		},
 		{
 			name:    "price too large",
-			dto:     CreateItemDTO{Name: "Widget", Price: 100000000},
+			dto:     CreateItemDTO{Name: "Widget", Price: MustParseMoney("100000000")},
 			wantErr: "price exceeds maximum allowed value",
 		},
+		{
+			name:    "too many decimal places",
+			dto:     CreateItemDTO{Name: "Widget", Price: Money{d: decimal.RequireFromString("12.345")}},
+			wantErr: "price must have at most two decimal places",
+		},
+		{
+			name:    "reports every failure",
+			dto:     CreateItemDTO{Name: "", Price: MustParseMoney("-1.0")},
+			wantErr: "name is required; price must be non-negative",
+		},
 	}
 
 	for _, tt := range tests {
@@ -68,7 +83,7 @@ func TestCreateItemDTO_Validate(t *testing.T) {
 
 func TestUpdateItemDTO_Validate(t *testing.T) {
 	strPtr := func(s string) *string { return &s }
-	floatPtr := func(f float64) *float64 { return &f }
+	moneyPtr := func(s string) *Money { m := MustParseMoney(s); return &m }
 
 	tests := []struct {
 		name    string
@@ -82,7 +97,7 @@ func TestUpdateItemDTO_Validate(t *testing.T) {
 		},
 		{
 			name:    "valid update price only",
-			dto:     UpdateItemDTO{Price: floatPtr(19.99)},
+			dto:     UpdateItemDTO{Price: moneyPtr("19.99")},
 			wantErr: "",
 		},
 		{
@@ -102,7 +117,7 @@ func TestUpdateItemDTO_Validate(t *testing.T) {
 		},
 		{
 			name:    "negative price",
-			dto:     UpdateItemDTO{Price: floatPtr(-5.0)},
+			dto:     UpdateItemDTO{Price: moneyPtr("-5.0")},
 			wantErr: "price must be non-negative",
 		},
 	}
@@ -124,3 +139,134 @@ func TestUpdateItemDTO_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSort(t *testing.T) {
+	fields, err := ParseSort("price, -name")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []SortField{{Column: "price"}, {Column: "name", Desc: true}}
+	if len(fields) != len(want) || fields[0] != want[0] || fields[1] != want[1] {
+		t.Errorf("expected %+v, got %+v", want, fields)
+	}
+
+	if fields, err := ParseSort(""); err != nil || fields != nil {
+		t.Errorf("expected empty sort, got %+v, %v", fields, err)
+	}
+	if _, err := ParseSort("id;DROP TABLE items"); err == nil {
+		t.Error("expected error for non-whitelisted column")
+	}
+	if _, err := ParseSort("price,-price"); err == nil {
+		t.Error("expected error for duplicate column")
+	}
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	c := Cursor{
+		CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC),
+		ID:        uuid.MustParse("550e8400-e29b-41d4-a716-446655440000"),
+		Backward:  true,
+	}
+
+	got, err := DecodeCursor(c.Encode())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.CreatedAt.Equal(c.CreatedAt) || got.ID != c.ID || got.Backward != c.Backward {
+		t.Errorf("expected %+v, got %+v", c, got)
+	}
+
+	for _, bad := range []string{"not base64!", "e30", ""} {
+		if _, err := DecodeCursor(bad); err == nil {
+			t.Errorf("expected error decoding %q", bad)
+		}
+	}
+}
+
+func TestValidate_FieldErrors(t *testing.T) {
+	empty := ""
+	price := MustParseMoney("-1")
+	dto := UpdateItemDTO{Name: &empty, Price: &price}
+
+	err := dto.Validate()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors, got %T", err)
+	}
+	want := ValidationErrors{
+		{Field: "name", Message: "name cannot be empty"},
+		{Field: "price", Message: "price must be non-negative"},
+	}
+	if len(verrs) != len(want) || verrs[0] != want[0] || verrs[1] != want[1] {
+		t.Errorf("expected %+v, got %+v", want, verrs)
+	}
+
+	valid := CreateItemDTO{Name: "Widget", Price: MustParseMoney("1")}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected untyped nil error, got %#v", err)
+	}
+}
+
+func TestCreateItemDTO_ValidateCurrency(t *testing.T) {
+	dto := CreateItemDTO{Name: "Widget", Price: MustParseMoney("10")}
+	if err := dto.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dto.Currency != DefaultCurrency() {
+		t.Errorf("expected default currency %s, got %q", DefaultCurrency(), dto.Currency)
+	}
+
+	dto = CreateItemDTO{
+		Name:     "Widget",
+		Price:    MustParseMoney("10"),
+		Currency: " eur ",
+		Prices:   map[string]Money{"usd": MustParseMoney("11.50"), "GBP": MustParseMoney("-1")},
+	}
+	err := dto.Validate()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 1 || verrs[0].Field != "prices.GBP" {
+		t.Fatalf("expected a single prices.GBP error, got %v", err)
+	}
+	if dto.Currency != "EUR" {
+		t.Errorf("expected normalised currency EUR, got %q", dto.Currency)
+	}
+	if _, ok := dto.Prices["USD"]; !ok {
+		t.Errorf("expected normalised price list key USD, got %v", dto.Prices)
+	}
+
+	for _, bad := range []CreateItemDTO{
+		{Name: "Widget", Currency: "XXX"},
+		{Name: "Widget", Currency: "EUR", Prices: map[string]Money{"EUR": MustParseMoney("1")}},
+		{Name: "Widget", Prices: map[string]Money{"DOLLARS": MustParseMoney("1")}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	before := ItemSnapshot{Name: "Widget", Price: MustParseMoney("9.99"), Currency: "USD"}
+	after := before
+	after.Price = MustParseMoney("12.50")
+
+	diff := DiffSnapshots(&before, &after)
+	if len(diff) != 1 {
+		t.Fatalf("expected only price to change, got %v", diff)
+	}
+	if got := diff["price"]; string(got.From) != "9.99" || string(got.To) != "12.50" {
+		t.Errorf("unexpected price change %s -> %s", got.From, got.To)
+	}
+
+	if diff := DiffSnapshots(&before, &before); diff != nil {
+		t.Errorf("expected no diff for identical snapshots, got %v", diff)
+	}
+
+	created := DiffSnapshots(nil, &before)
+	if string(created["name"].From) != "null" || string(created["name"].To) != `"Widget"` {
+		t.Errorf("unexpected creation diff %v", created)
+	}
+	if _, ok := created["prices"]; ok {
+		t.Error("expected empty price list omitted from creation diff")
+	}
+}
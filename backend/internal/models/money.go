@@ -0,0 +1,156 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+)
+
+// MoneyScale is the number of fractional digits stored for prices,
+// matching the DECIMAL(10, 2) column.
+const MoneyScale = 2
+
+// MaxPrice is the largest value a DECIMAL(10, 2) column can hold.
+var MaxPrice = MustParseMoney("99999999.99")
+
+// maxMoneyDigits and maxMoneyExponent bound the amounts ParseMoney
+// accepts to what DECIMAL(10, 2) can hold. The exponent allows 1e8 so
+// that a price just over MaxPrice still reaches validation.
+const (
+	maxMoneyDigits   = 10
+	maxMoneyExponent = 8
+)
+
+// MoneyJSONFormat selects how Money values are written to JSON.
+type MoneyJSONFormat int32
+
+const (
+	// MoneyAsNumber writes prices as JSON numbers, e.g. 9.90.
+	MoneyAsNumber MoneyJSONFormat = iota
+	// MoneyAsString writes prices as JSON strings, e.g. "9.90", for
+	// clients that would otherwise parse them into binary floats.
+	MoneyAsString
+)
+
+var moneyJSONFormat atomic.Int32
+
+// SetMoneyJSONFormat sets the process-wide JSON encoding of Money.
+func SetMoneyJSONFormat(f MoneyJSONFormat) {
+	moneyJSONFormat.Store(int32(f))
+}
+
+// ParseMoneyJSONFormat maps the "number" / "string" option names.
+func ParseMoneyJSONFormat(s string) (MoneyJSONFormat, error) {
+	switch s {
+	case "", "number":
+		return MoneyAsNumber, nil
+	case "string":
+		return MoneyAsString, nil
+	}
+	return 0, fmt.Errorf("unknown price format %q (want number or string)", s)
+}
+
+// Money is an exact decimal amount. It decodes from JSON numbers or
+// strings without passing through float64 and scans Postgres NUMERIC
+// values directly.
+type Money struct {
+	d decimal.Decimal
+}
+
+// ParseMoney parses a decimal amount, rejecting any that has more
+// significant digits or fractional digits than DECIMAL(10, 2) can hold.
+// The check runs before anything rescales the value: comparing or
+// rounding 1e99999999 would build a hundred-million-digit integer.
+func ParseMoney(s string) (Money, error) {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	if !inMoneyRange(d) {
+		return Money{}, fmt.Errorf("amount %q is out of range", s)
+	}
+	return Money{d: d}, nil
+}
+
+// inMoneyRange reports whether d, with trailing zeros dropped from its
+// coefficient, has at most maxMoneyDigits digits and an exponent between
+// -MoneyScale and maxMoneyExponent. It works on the digit string so
+// that long runs of zeros cost linear time.
+func inMoneyRange(d decimal.Decimal) bool {
+	digits := strings.TrimLeft(d.Coefficient().String(), "-")
+	if digits == "0" {
+		return true
+	}
+	significant := strings.TrimRight(digits, "0")
+	exp := int64(d.Exponent()) + int64(len(digits)-len(significant))
+	return len(significant) <= maxMoneyDigits && exp >= -MoneyScale && exp <= maxMoneyExponent
+}
+
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m Money) Cmp(o Money) int { return m.d.Cmp(o.d) }
+
+func (m Money) Equal(o Money) bool { return m.d.Equal(o.d) }
+
+func (m Money) IsNegative() bool { return m.d.IsNegative() }
+
+// HasValidScale reports whether m has at most MoneyScale fractional digits.
+// Trailing zeros do not count, so 1.500 is valid.
+func (m Money) HasValidScale() bool {
+	return m.d.Equal(m.d.Round(MoneyScale))
+}
+
+// String formats m with exactly MoneyScale fractional digits when it has
+// no more than that, and exactly otherwise.
+func (m Money) String() string {
+	if m.HasValidScale() {
+		return m.d.StringFixed(MoneyScale)
+	}
+	return m.d.String()
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	if MoneyJSONFormat(moneyJSONFormat.Load()) == MoneyAsString {
+		return []byte(`"` + m.String() + `"`), nil
+	}
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	parsed, err := ParseMoney(string(bytes.Trim(data, `"`)))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// ScanNumeric implements pgtype.NumericScanner.
+func (m *Money) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		return fmt.Errorf("cannot scan NULL into Money")
+	}
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("cannot scan non-finite numeric into Money")
+	}
+	m.d = decimal.NewFromBigInt(v.Int, v.Exp)
+	return nil
+}
+
+// NumericValue implements pgtype.NumericValuer.
+func (m Money) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: m.d.Coefficient(), Exp: m.d.Exponent(), Valid: true}, nil
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMoney_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`12.34`, "12.34"},
+		{`"12.34"`, "12.34"},
+		{`0.1`, "0.10"},
+		{`1e2`, "100.00"},
+		{`1.500`, "1.50"},
+		{`1e8`, "100000000.00"},
+		{`"-0.5"`, "-0.50"},
+	}
+
+	for _, tt := range tests {
+		var m Money
+		if err := json.Unmarshal([]byte(tt.input), &m); err != nil {
+			t.Errorf("%s: unexpected error %v", tt.input, err)
+			continue
+		}
+		if m.String() != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.want, m.String())
+		}
+	}
+
+	var m Money
+	if err := json.Unmarshal([]byte(`"twelve"`), &m); err == nil {
+		t.Error("expected error for non-numeric string")
+	}
+}
+
+func TestParseMoney_OutOfRange(t *testing.T) {
+	for _, s := range []string{
+		"1e99999999",
+		"-1e99999999",
+		"1e-99999999",
+		"12.345",
+		"99999999.995",
+		"12345678901",
+		"1e9",
+		"0.001",
+	} {
+		if _, err := ParseMoney(s); err == nil {
+			t.Errorf("ParseMoney(%q): expected an error", s)
+		}
+	}
+
+	var dto CreateItemDTO
+	err := json.Unmarshal([]byte(`{"name":"Widget","price":1e99999999,"prices":{"EUR":"1e99999999"}}`), &dto)
+	if err == nil {
+		t.Error("expected JSON decoding of 1e99999999 to fail")
+	}
+	var m Money
+	if err := json.Unmarshal([]byte(`"1e99999999"`), &m); err == nil {
+		t.Error("expected JSON decoding of \"1e99999999\" to fail")
+	}
+}
+
+func TestMoney_MarshalJSON(t *testing.T) {
+	defer SetMoneyJSONFormat(MoneyAsNumber)
+	item := struct {
+		Price Money `json:"price"`
+	}{Price: MustParseMoney("9.9")}
+
+	b, _ := json.Marshal(item)
+	if string(b) != `{"price":9.90}` {
+		t.Errorf("number format: got %s", b)
+	}
+
+	SetMoneyJSONFormat(MoneyAsString)
+	b, _ = json.Marshal(item)
+	if string(b) != `{"price":"9.90"}` {
+		t.Errorf("string format: got %s", b)
+	}
+}
+
+func TestMoney_ExactArithmeticAndScale(t *testing.T) {
+	var a, b Money
+	json.Unmarshal([]byte(`0.1`), &a)
+	json.Unmarshal([]byte(`0.2`), &b)
+	sum := Money{d: a.d.Add(b.d)}
+	if !sum.Equal(MustParseMoney("0.3")) {
+		t.Errorf("expected 0.1 + 0.2 == 0.3, got %s", sum)
+	}
+
+	if !MustParseMoney("1.500").HasValidScale() {
+		t.Error("trailing zeros should not count as fractional digits")
+	}
+	if (Money{d: decimal.RequireFromString("12.345")}).HasValidScale() {
+		t.Error("expected 12.345 to have too many fractional digits")
+	}
+	if MustParseMoney("100000000").Cmp(MaxPrice) <= 0 {
+		t.Error("expected 100000000 to exceed MaxPrice")
+	}
+}
+
+func TestMoney_NumericRoundTrip(t *testing.T) {
+	in := MustParseMoney("1234.56")
+	n, err := in.NumericValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out Money
+	if err := out.ScanNumeric(n); err != nil {
+		t.Fatal(err)
+	}
+	if !out.Equal(in) {
+		t.Errorf("expected %s, got %s", in, out)
+	}
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"maps"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Revision actions recorded in an item's history.
+const (
+	ActionCreated  = "created"
+	ActionUpdated  = "updated"
+	ActionDeleted  = "deleted"
+	ActionRestored = "restored"
+	ActionReverted = "reverted"
+)
+
+// ItemSnapshot is the user-editable state of an item at one revision.
+type ItemSnapshot struct {
+	Name     string           `json:"name"`
+	Price    Money            `json:"price"`
+	Currency string           `json:"currency"`
+	Prices   map[string]Money `json:"prices,omitempty"`
+}
+
+func (i *Item) Snapshot() ItemSnapshot {
+	return ItemSnapshot{Name: i.Name, Price: i.Price, Currency: i.Currency, Prices: i.Prices}
+}
+
+// FieldChange records the old and new JSON values of one field.
+type FieldChange struct {
+	From json.RawMessage `json:"from"`
+	To   json.RawMessage `json:"to"`
+}
+
+// ItemRevision is one immutable entry in an item's history. Version is the
+// item version the change produced.
+type ItemRevision struct {
+	ItemID    uuid.UUID              `json:"item_id"`
+	Version   int64                  `json:"version"`
+	Action    string                 `json:"action"`
+	Actor     string                 `json:"actor"`
+	ChangedAt time.Time              `json:"changed_at"`
+	Snapshot  ItemSnapshot           `json:"snapshot"`
+	Diff      map[string]FieldChange `json:"diff,omitempty"`
+}
+
+// DiffSnapshots lists the fields that differ between before and after. A
+// nil before (creation) reports every field as changed from null.
+func DiffSnapshots(before *ItemSnapshot, after *ItemSnapshot) map[string]FieldChange {
+	diff := make(map[string]FieldChange)
+	var prev ItemSnapshot
+	if before != nil {
+		prev = *before
+	}
+
+	add := func(field string, from, to any, changed bool) {
+		if before != nil && !changed {
+			return
+		}
+		fromJSON := json.RawMessage("null")
+		if before != nil {
+			fromJSON, _ = json.Marshal(from)
+		}
+		toJSON, _ := json.Marshal(to)
+		diff[field] = FieldChange{From: fromJSON, To: toJSON}
+	}
+
+	add("name", prev.Name, after.Name, prev.Name != after.Name)
+	add("price", prev.Price, after.Price, !prev.Price.Equal(after.Price))
+	add("currency", prev.Currency, after.Currency, prev.Currency != after.Currency)
+	if before != nil || len(after.Prices) > 0 {
+		add("prices", prev.Prices, after.Prices, !maps.EqualFunc(prev.Prices, after.Prices, Money.Equal))
+	}
+
+	if len(diff) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// ItemHistory is the response body of the history endpoint.
+type ItemHistory struct {
+	ItemID    uuid.UUID      `json:"item_id"`
+	Revisions []ItemRevision `json:"revisions"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"html"
+	"strings"
+)
+
+// Highlight markers wrapped around matched words in ItemSearchResult.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// MatchStart and MatchStop delimit matched words while a highlight is
+// built. They are private-use characters, so they survive HTML escaping
+// and cannot be confused with markup in the name.
+const (
+	MatchStart = "\ue000"
+	MatchStop  = "\ue001"
+)
+
+var highlightMarkers = strings.NewReplacer(MatchStart, HighlightStart, MatchStop, HighlightStop)
+
+// RenderHighlight HTML-escapes a name whose matched words are delimited
+// by MatchStart and MatchStop and then turns the delimiters into
+// HighlightStart and HighlightStop.
+func RenderHighlight(matched string) string {
+	return highlightMarkers.Replace(html.EscapeString(matched))
+}
+
+// ItemSearchQuery describes one page of a ranked name search. Filter
+// narrows the candidates the same way it narrows a listing.
+type ItemSearchQuery struct {
+	Text   string
+	Filter ItemFilter
+	Limit  int
+	Offset int
+}
+
+// Validate normalises the search text. The filter is validated when it is
+// parsed.
+func (q *ItemSearchQuery) Validate() error {
+	var errs ValidationErrors
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Text == "" {
+		errs.Add("q", "q is required")
+	} else if len(q.Text) > 255 {
+		errs.Add("q", "q must be 255 characters or less")
+	}
+	return errs.Err()
+}
+
+// ItemSearchResult is an item matched by a search. Rank only orders the
+// results of one query; Highlight is the HTML-escaped item name with
+// matched words wrapped in HighlightStart and HighlightStop.
+type ItemSearchResult struct {
+	Item
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+type ItemSearchResponse struct {
+	Query   string             `json:"query"`
+	Results []ItemSearchResult `json:"results"`
+	Limit   int                `json:"limit"`
+	Offset  int                `json:"offset"`
+}
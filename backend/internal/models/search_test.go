@@ -0,0 +1,17 @@
+package models
+
+import "testing"
+
+func TestRenderHighlight(t *testing.T) {
+	tests := map[string]string{
+		"Blue " + MatchStart + "Widget" + MatchStop:                         "Blue <mark>Widget</mark>",
+		`<img src=x onerror=alert(1)> ` + MatchStart + "Widget" + MatchStop: "&lt;img src=x onerror=alert(1)&gt; <mark>Widget</mark>",
+		MatchStart + "Tom & Jerry's" + MatchStop:                            "<mark>Tom &amp; Jerry&#39;s</mark>",
+		"<mark>plain</mark>":                                                "&lt;mark&gt;plain&lt;/mark&gt;",
+	}
+	for in, want := range tests {
+		if got := RenderHighlight(in); got != want {
+			t.Errorf("RenderHighlight(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package models
+
+// ItemStats summarises the items of a tenant, or of every tenant, for
+// monitoring. The per-minute counts cover the minute before the stats
+// were taken.
+type ItemStats struct {
+	Live              int64
+	Trashed           int64
+	CreatedLastMinute int64
+	DeletedLastMinute int64
+}
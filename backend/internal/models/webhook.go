@@ -0,0 +1,201 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook event types. Restores and reverts are reported as updates.
+const (
+	EventItemCreated = "item.created"
+	EventItemUpdated = "item.updated"
+	EventItemDeleted = "item.deleted"
+)
+
+// WebhookEventTypes lists every event a webhook may subscribe to.
+var WebhookEventTypes = []string{EventItemCreated, EventItemUpdated, EventItemDeleted}
+
+// WebhookEventType maps a revision action to the event it announces.
+func WebhookEventType(action string) string {
+	switch action {
+	case ActionCreated:
+		return EventItemCreated
+	case ActionDeleted:
+		return EventItemDeleted
+	}
+	return EventItemUpdated
+}
+
+// Webhook delivery statuses. A failed delivery has used up its attempts
+// and is only retried by an explicit redeliver.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// minWebhookSecret is the shortest secret accepted from clients.
+const minWebhookSecret = 16
+
+// Webhook is a subscription to item events. Secret signs every delivery;
+// it is only returned when it is set.
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Subscribed reports whether the webhook wants events of type event.
+func (h *Webhook) Subscribed(event string) bool {
+	return h.Active && slices.Contains(h.Events, event)
+}
+
+type CreateWebhookDTO struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"`
+	Secret string   `json:"secret,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+// Validate normalises the DTO: no events means every event, no secret a
+// freshly generated one and no active flag an active webhook.
+func (d *CreateWebhookDTO) Validate() error {
+	var errs ValidationErrors
+	validateWebhookURL(d.URL, &errs)
+	d.Events = validateWebhookEvents(d.Events, &errs)
+	if d.Secret == "" {
+		d.Secret = NewWebhookSecret()
+	}
+	validateWebhookSecret(d.Secret, &errs)
+	if d.Active == nil {
+		active := true
+		d.Active = &active
+	}
+	return errs.Err()
+}
+
+type UpdateWebhookDTO struct {
+	URL    *string   `json:"url,omitempty"`
+	Events *[]string `json:"events,omitempty"`
+	Secret *string   `json:"secret,omitempty"`
+	Active *bool     `json:"active,omitempty"`
+}
+
+func (d *UpdateWebhookDTO) Validate() error {
+	var errs ValidationErrors
+	if d.URL != nil {
+		validateWebhookURL(*d.URL, &errs)
+	}
+	if d.Events != nil {
+		events := validateWebhookEvents(*d.Events, &errs)
+		d.Events = &events
+	}
+	if d.Secret != nil {
+		validateWebhookSecret(*d.Secret, &errs)
+	}
+	return errs.Err()
+}
+
+func validateWebhookURL(raw string, errs *ValidationErrors) {
+	u, err := url.Parse(raw)
+	if raw == "" {
+		errs.Add("url", "url is required")
+	} else if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs.Add("url", "url must be an absolute http or https URL")
+	}
+}
+
+// validateWebhookEvents checks and de-duplicates events, defaulting to
+// every event type.
+func validateWebhookEvents(events []string, errs *ValidationErrors) []string {
+	if len(events) == 0 {
+		return slices.Clone(WebhookEventTypes)
+	}
+	var out []string
+	for _, e := range events {
+		if !slices.Contains(WebhookEventTypes, e) {
+			errs.Add("events", "unknown event "+e)
+			continue
+		}
+		if !slices.Contains(out, e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func validateWebhookSecret(secret string, errs *ValidationErrors) {
+	if len(secret) < minWebhookSecret {
+		errs.Add("secret", "secret must be at least 16 characters")
+	}
+}
+
+// NewWebhookSecret returns a random 256-bit secret, hex encoded.
+func NewWebhookSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// WebhookEvent is the JSON body POSTed to a webhook. ID is shared by every
+// delivery of the same event, so receivers can drop duplicates.
+type WebhookEvent struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      *Item     `json:"data"`
+}
+
+// NewWebhookEvent builds the event announcing a revision of item.
+func NewWebhookEvent(action string, item *Item, at time.Time) WebhookEvent {
+	return WebhookEvent{ID: uuid.New(), Type: WebhookEventType(action), CreatedAt: at, Data: item}
+}
+
+// WebhookDelivery is one event queued for, or sent to, one webhook. The
+// deliveries table is the transactional outbox: rows are written in the
+// same transaction as the change they announce.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      uuid.UUID       `json:"webhook_id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseStatus *int            `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// PendingDelivery is a delivery claimed for sending, with the target it
+// goes to.
+type PendingDelivery struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// DeliveryAttempt is the outcome of one attempt to send a delivery. A nil
+// RetryAt on an undelivered attempt marks the delivery as failed.
+type DeliveryAttempt struct {
+	At             time.Time
+	Delivered      bool
+	ResponseStatus *int
+	Error          string
+	RetryAt        *time.Time
+}
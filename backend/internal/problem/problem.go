@@ -0,0 +1,88 @@
+// Package problem writes RFC 7807 application/problem+json error responses.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem type URIs. Responses without extra semantics use TypeBlank and
+// the HTTP status text as their title.
+const (
+	TypeBlank      = "about:blank"
+	TypeValidation = "/problems/validation-error"
+)
+
+type Details struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+	TraceID   string              `json:"trace_id,omitempty"`
+	Errors    []models.FieldError `json:"errors,omitempty"`
+}
+
+func New(status int, detail string) *Details {
+	return &Details{
+		Type:   TypeBlank,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Write sends p, filling in the request path, id and trace id.
+func Write(w http.ResponseWriter, r *http.Request, p *Details) {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = middleware.GetReqID(r.Context())
+	}
+	if sc := trace.SpanContextFromContext(r.Context()); p.TraceID == "" && sc.HasTraceID() {
+		p.TraceID = sc.TraceID().String()
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error writes a generic problem for status with a human-readable detail.
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	Write(w, r, New(status, detail))
+}
+
+// Internal logs err and writes a 500 problem with detail, so the cause
+// reaches the logs but never the client.
+func Internal(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	slog.ErrorContext(r.Context(), detail, "error", err)
+	Error(w, r, http.StatusInternalServerError, detail)
+}
+
+// Validation writes a 400 problem listing every failed field of err. Errors
+// that are not models.ValidationErrors are reported as the detail only.
+func Validation(w http.ResponseWriter, r *http.Request, err error) {
+	p := New(http.StatusBadRequest, err.Error())
+
+	var verrs models.ValidationErrors
+	if errors.As(err, &verrs) {
+		p.Type = TypeValidation
+		p.Title = "Validation failed"
+		p.Detail = "One or more fields are invalid"
+		p.Errors = verrs
+	}
+
+	Write(w, r, p)
+}
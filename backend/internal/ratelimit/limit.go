@@ -0,0 +1,75 @@
+// Package ratelimit throttles API clients with token buckets, keyed by
+// the authenticated caller or else the client IP.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows bursts of up to Requests requests, refilled at Requests
+// per Per. The zero Limit allows everything.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit parses a limit written as requests/duration, such as
+// "600/1m", or "off" for no limit.
+func ParseLimit(s string) (Limit, error) {
+	if s == "off" {
+		return Limit{}, nil
+	}
+	n, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%q is not of the form requests/duration", s)
+	}
+	requests, err := strconv.Atoi(n)
+	if err != nil || requests < 1 {
+		return Limit{}, fmt.Errorf("%q: requests must be a positive integer", s)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d < time.Second {
+		return Limit{}, fmt.Errorf("%q: duration must be at least 1s", s)
+	}
+	return Limit{Requests: requests, Per: d}, nil
+}
+
+func (l Limit) String() string {
+	if l.Requests == 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// Interval is the time it takes to refill one token.
+func (l Limit) Interval() time.Duration {
+	return l.Per / time.Duration(l.Requests)
+}
+
+// Take spends a token of a bucket at now. Buckets are tracked as the
+// time they will be full again (their theoretical arrival time in GCRA
+// terms), which a single timestamp can hold: a bucket whose time is in
+// the past is full. Take returns the bucket's new time and true, or its
+// unchanged time and false when the bucket is empty.
+func (l Limit) Take(full, now time.Time) (time.Time, bool) {
+	if full.Before(now) {
+		full = now
+	}
+	next := full.Add(l.Interval())
+	if next.Sub(now) > l.Per {
+		return full, false
+	}
+	return next, true
+}
+
+// Store keeps the bucket of every key.
+type Store interface {
+	// TakeToken applies l.Take to the bucket of key atomically.
+	TakeToken(ctx context.Context, key string, l Limit, now time.Time) (time.Time, bool, error)
+	// SweepBuckets forgets buckets that are full at now.
+	SweepBuckets(ctx context.Context, now time.Time) error
+}
@@ -0,0 +1,189 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/internal/identity"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+)
+
+// Limiter applies Read to GET, HEAD and OPTIONS requests and Write to the
+// others, with a separate bucket per client for each. IP, applied by
+// IPMiddleware, bounds every request from one client IP.
+type Limiter struct {
+	Store Store
+	Read  Limit
+	Write Limit
+	IP    Limit
+	// TrustedProxies are the addresses whose X-Forwarded-For entries are
+	// believed when working out the client IP.
+	TrustedProxies []netip.Prefix
+	now            func() time.Time
+}
+
+func NewLimiter(store Store, read, write Limit, trustedProxies []netip.Prefix) *Limiter {
+	return &Limiter{
+		Store:          store,
+		Read:           read,
+		Write:          write,
+		TrustedProxies: trustedProxies,
+		now:            time.Now,
+	}
+}
+
+// Middleware spends a token of the caller's bucket on every request and
+// reports the bucket in the RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset and RateLimit-Policy headers. Requests finding the
+// bucket empty get 429 with Retry-After. It must run after
+// authentication, so that callers are told apart by identity rather than
+// IP. If the store fails, requests are let through.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class, limit := "write", l.Write
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			class, limit = "read", l.Read
+		}
+		if l.take(w, r, class, limit, class+":"+l.clientKey(r)) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// IPMiddleware spends a token of the client IP's IP bucket on every
+// request. It runs before authentication, so that requests with missing
+// or wrong credentials, which never reach Middleware, are limited too
+// before they cost a key lookup.
+func (l *Limiter) IPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.take(w, r, "any", l.IP, "any:ip:"+l.clientIP(r)) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// take spends a token of the bucket under key and sets the RateLimit
+// headers. It reports whether the request may go on, having written a
+// 429 if not.
+func (l *Limiter) take(w http.ResponseWriter, r *http.Request, class string, limit Limit, key string) bool {
+	if limit.Requests == 0 {
+		return true
+	}
+
+	now := l.now()
+	full, ok, err := l.Store.TakeToken(r.Context(), key, limit, now)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Rate limiting skipped", "error", err)
+		return true
+	}
+
+	used := full.Sub(now)
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+	h.Set("RateLimit-Remaining", strconv.Itoa(int((limit.Per-used)/limit.Interval())))
+	h.Set("RateLimit-Reset", strconv.Itoa(seconds(used)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, seconds(limit.Per)))
+	if !ok {
+		retry := max(seconds(used+limit.Interval()-limit.Per), 1)
+		h.Set("Retry-After", strconv.Itoa(retry))
+		problem.Error(w, r, http.StatusTooManyRequests, fmt.Sprintf("Rate limit of %d %s requests per %s exceeded; retry in %d seconds", limit.Requests, class, limit.Per, retry))
+		return false
+	}
+	return true
+}
+
+// Run forgets full buckets every interval until ctx is done.
+func (l *Limiter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := l.Store.SweepBuckets(ctx, l.now()); err != nil {
+			slog.Error("Rate limit sweep failed", "error", err)
+		}
+	}
+}
+
+// clientKey identifies the caller: its subject when authenticated, which
+// covers both users and API keys, or else its IP.
+func (l *Limiter) clientKey(r *http.Request) string {
+	if id, ok := identity.FromContext(r.Context()); ok {
+		return "sub:" + id.Subject
+	}
+	return "ip:" + l.clientIP(r)
+}
+
+// clientIP is the peer address, unless the peer is a trusted proxy: then
+// X-Forwarded-For is walked from the right, skipping trusted proxies, and
+// the first other address is the client.
+func (l *Limiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	ip = ip.Unmap()
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for n := len(hops) - 1; n >= 0 && l.trusted(ip); n-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[n]))
+		if err != nil {
+			break
+		}
+		ip = hop.Unmap()
+	}
+	return ip.String()
+}
+
+func (l *Limiter) trusted(ip netip.Addr) bool {
+	for _, p := range l.TrustedProxies {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTrustedProxies parses a comma-separated list of IP addresses and
+// CIDR ranges.
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if ip, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(ip.Unmap(), ip.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR range", entry)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// seconds rounds d up to whole seconds.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,201 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/internal/identity"
+)
+
+func TestParseLimit(t *testing.T) {
+	if l, err := ParseLimit("600/1m"); err != nil || l != (Limit{Requests: 600, Per: time.Minute}) {
+		t.Fatalf("got %+v, %v", l, err)
+	}
+	if l, err := ParseLimit("off"); err != nil || l.Requests != 0 {
+		t.Fatalf("got %+v, %v", l, err)
+	}
+	for _, s := range []string{"", "600", "0/1m", "-1/1m", "10/1ms", "10/minute"} {
+		if _, err := ParseLimit(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestLimit_Take(t *testing.T) {
+	l := Limit{Requests: 3, Per: 3 * time.Second}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var full time.Time
+	for n := 0; n < 3; n++ {
+		var ok bool
+		if full, ok = l.Take(full, now); !ok {
+			t.Fatalf("request %d: expected the burst to be allowed", n)
+		}
+	}
+	if _, ok := l.Take(full, now); ok {
+		t.Fatal("expected the fourth request to be refused")
+	}
+	// One token is back after one interval.
+	full, ok := l.Take(full, now.Add(time.Second))
+	if !ok {
+		t.Fatal("expected a refilled token to be allowed")
+	}
+	if _, ok := l.Take(full, now.Add(time.Second)); ok {
+		t.Fatal("expected only one token to have been refilled")
+	}
+}
+
+type failingStore struct{ *MemoryStore }
+
+func (failingStore) TakeToken(context.Context, string, Limit, time.Time) (time.Time, bool, error) {
+	return time.Time{}, false, errors.New("store down")
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(NewMemoryStore(), Limit{Requests: 2, Per: time.Minute}, Limit{Requests: 1, Per: time.Minute}, nil)
+	l.now = func() time.Time { return now }
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(method, addr, subject string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/items", nil)
+		req.RemoteAddr = addr
+		if subject != "" {
+			req = req.WithContext(identity.WithIdentity(req.Context(), &identity.Identity{Subject: subject}))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodGet, "192.0.2.1:1234", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "2" || rec.Header().Get("RateLimit-Remaining") != "1" ||
+		rec.Header().Get("RateLimit-Reset") != "30" || rec.Header().Get("RateLimit-Policy") != "2;w=60" {
+		t.Fatalf("first read: got %d %v", rec.Code, rec.Header())
+	}
+	do(http.MethodGet, "192.0.2.1:1234", "")
+	rec = do(http.MethodGet, "192.0.2.1:1234", "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("third read: got %d %v", rec.Code, rec.Header())
+	}
+
+	// Writes, other clients and authenticated callers have buckets of
+	// their own.
+	if rec := do(http.MethodPost, "192.0.2.1:1234", ""); rec.Code != http.StatusOK {
+		t.Fatalf("first write: got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "192.0.2.1:1234", ""); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second write: got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "192.0.2.2:1234", ""); rec.Code != http.StatusOK {
+		t.Fatalf("other client: got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "192.0.2.1:1234", "alice"); rec.Code != http.StatusOK {
+		t.Fatalf("authenticated caller: got %d", rec.Code)
+	}
+
+	now = now.Add(30 * time.Second)
+	if rec := do(http.MethodGet, "192.0.2.1:1234", ""); rec.Code != http.StatusOK {
+		t.Fatalf("after refill: got %d", rec.Code)
+	}
+
+	l.Store = failingStore{NewMemoryStore()}
+	if rec := do(http.MethodGet, "192.0.2.1:1234", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected requests to pass when the store fails, got %d", rec.Code)
+	}
+}
+
+func TestLimiter_IPMiddleware(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(NewMemoryStore(), Limit{Requests: 5, Per: time.Minute}, Limit{Requests: 5, Per: time.Minute}, nil)
+	l.IP = Limit{Requests: 2, Per: time.Minute}
+	l.now = func() time.Time { return now }
+	// Stands in for authentication turning away bad credentials.
+	h := l.IPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	do := func(method, addr string) int {
+		req := httptest.NewRequest(method, "/api/items", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Reads and writes share the one bucket.
+	if code := do(http.MethodGet, "192.0.2.1:1234"); code != http.StatusUnauthorized {
+		t.Fatalf("first request: got %d", code)
+	}
+	if code := do(http.MethodPost, "192.0.2.1:1234"); code != http.StatusUnauthorized {
+		t.Fatalf("second request: got %d", code)
+	}
+	if code := do(http.MethodGet, "192.0.2.1:1234"); code != http.StatusTooManyRequests {
+		t.Fatalf("third request: got %d, want 429", code)
+	}
+	if code := do(http.MethodGet, "192.0.2.2:1234"); code != http.StatusUnauthorized {
+		t.Fatalf("other client: got %d", code)
+	}
+
+	l.IP = Limit{}
+	if code := do(http.MethodGet, "192.0.2.1:1234"); code != http.StatusUnauthorized {
+		t.Fatalf("with the IP limit off: got %d", code)
+	}
+}
+
+func TestLimiter_ClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 192.0.2.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLimiter(NewMemoryStore(), Limit{}, Limit{}, proxies)
+
+	tests := []struct {
+		remote string
+		xff    string
+		want   string
+	}{
+		{"198.51.100.1:80", "", "198.51.100.1"},
+		{"198.51.100.1:80", "203.0.113.9", "198.51.100.1"},
+		{"10.1.2.3:80", "203.0.113.9", "203.0.113.9"},
+		{"10.1.2.3:80", "203.0.113.9, 192.0.2.7, 10.0.0.1", "203.0.113.9"},
+		{"10.1.2.3:80", "6.6.6.6, 203.0.113.9", "203.0.113.9"},
+		{"10.1.2.3:80", "garbage", "10.1.2.3"},
+		{"10.1.2.3:80", "", "10.1.2.3"},
+		{"[::ffff:10.1.2.3]:80", "203.0.113.9", "203.0.113.9"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tt.remote
+		if tt.xff != "" {
+			req.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		if got := l.clientIP(req); got != tt.want {
+			t.Errorf("%s via %q: expected %s, got %s", tt.remote, tt.xff, tt.want, got)
+		}
+	}
+
+	if _, err := ParseTrustedProxies("10.0.0.0/8,nope"); err == nil {
+		t.Error("expected an invalid proxy to be rejected")
+	}
+}
+
+func TestMemoryStore_SweepBuckets(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	l := Limit{Requests: 1, Per: time.Minute}
+	s.TakeToken(context.Background(), "a", l, now)
+	s.TakeToken(context.Background(), "b", l, now.Add(time.Minute))
+
+	s.SweepBuckets(context.Background(), now.Add(time.Minute))
+	if _, ok := s.buckets["a"]; ok {
+		t.Error("expected the full bucket to be swept")
+	}
+	if _, ok := s.buckets["b"]; !ok {
+		t.Error("expected the used bucket to be kept")
+	}
+}
@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps buckets in process, so each replica limits clients
+// on its own.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]time.Time)}
+}
+
+func (s *MemoryStore) TakeToken(ctx context.Context, key string, l Limit, now time.Time) (time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	full, ok := l.Take(s.buckets[key], now)
+	if ok {
+		s.buckets[key] = full
+	}
+	return full, ok, nil
+}
+
+func (s *MemoryStore) SweepBuckets(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, full := range s.buckets {
+		if !full.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// apiKeyTouchInterval limits how often a key's last use is written, so a
+// busy client does not turn every request into a write.
+const apiKeyTouchInterval = time.Minute
+
+const apiKeyColumns = `id, tenant_id, name, prefix, key_hash, scopes, created_by, expires_at, last_used_at, created_at`
+
+func scanAPIKey(row pgx.Row, k *models.APIKey) error {
+	return row.Scan(&k.ID, &k.Tenant, &k.Name, &k.Prefix, &k.Hash, &k.Scopes, &k.CreatedBy, &k.ExpiresAt, &k.LastUsedAt, &k.CreatedAt)
+}
+
+func (r *ItemRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	query := `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + apiKeyColumns
+
+	var k models.APIKey
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		return scanAPIKey(tx.QueryRow(ctx, query, key.Name, key.Prefix, key.Hash, key.Scopes, key.CreatedBy, key.ExpiresAt), &k)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	return &k, nil
+}
+
+func (r *ItemRepository) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE `+tenantScope+` ORDER BY created_at, id`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var k models.APIKey
+			if err := scanAPIKey(rows, &k); err != nil {
+				return err
+			}
+			keys = append(keys, k)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *ItemRepository) DeleteAPIKey(ctx context.Context, id uuid.UUID) error {
+	var deleted int64
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, `DELETE FROM api_keys WHERE id = $1 AND `+tenantScope, id)
+		deleted = commandTag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete API key: %w", err)
+	}
+	if deleted == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *ItemRepository) APIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	var k models.APIKey
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		return scanAPIKey(tx.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix = $1 AND `+tenantScope, prefix), &k)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return &k, nil
+}
+
+func (r *ItemRepository) TouchAPIKey(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := `
+		UPDATE api_keys SET last_used_at = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at <= $3)
+	`
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, query, id, at, at.Add(-apiKeyTouchInterval))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record API key use: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// BulkOutcome is the result of one bulk operation. Item is nil for deletes
+// and failures.
+type BulkOutcome struct {
+	Item *models.Item
+	Err  error
+}
+
+// BulkError reports the operation that aborted an atomic bulk write.
+type BulkError struct {
+	Index int
+	Err   error
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("bulk operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BulkError) Unwrap() error { return e.Err }
+
+// Bulk applies validated operations in a single transaction. In atomic
+// mode the first failure rolls everything back and is returned as a
+// *BulkError; otherwise each operation runs in its own savepoint and its
+// failure is only reported in the outcome.
+func (r *ItemRepository) Bulk(ctx context.Context, ops []models.BulkOperation, atomic bool) ([]BulkOutcome, error) {
+	outcomes := make([]BulkOutcome, len(ops))
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		for n := range ops {
+			if atomic {
+				item, err := applyBulkOperation(ctx, tx, &ops[n])
+				if err != nil {
+					return &BulkError{Index: n, Err: err}
+				}
+				outcomes[n].Item = item
+				continue
+			}
+
+			err := pgx.BeginFunc(ctx, tx, func(sp pgx.Tx) (err error) {
+				outcomes[n].Item, err = applyBulkOperation(ctx, sp, &ops[n])
+				return err
+			})
+			outcomes[n].Err = err
+		}
+		return nil
+	})
+	if err != nil {
+		var bulkErr *BulkError
+		if errors.As(err, &bulkErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to apply bulk operations: %w", err)
+	}
+
+	return outcomes, nil
+}
+
+func applyBulkOperation(ctx context.Context, tx pgx.Tx, op *models.BulkOperation) (*models.Item, error) {
+	switch op.Op {
+	case models.BulkCreate:
+		return createItem(ctx, tx, op.Create)
+	case models.BulkUpdate:
+		return updateItem(ctx, tx, op.ID, op.Update, op.Version)
+	case models.BulkDelete:
+		return nil, deleteItem(ctx, tx, op.ID, op.Version)
+	}
+	return nil, fmt.Errorf("unknown bulk operation %q", op.Op)
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+// itemEventsChannel is the NOTIFY channel fed by the item_revisions trigger
+// from migration 000007.
+const itemEventsChannel = "item_events"
+
+// listenRetryDelay is how long Listen waits before reconnecting after the
+// listening connection fails.
+const listenRetryDelay = 2 * time.Second
+
+// eventReplayBatch is the page size used when replaying missed events.
+const eventReplayBatch = 500
+
+const itemEventColumns = `id, tenant_id, item_id, version, action, actor, changed_at, snapshot, diff`
+
+func scanItemEvent(row pgx.Row, e *models.ItemEvent) error {
+	return row.Scan(&e.ID, &e.Tenant, &e.ItemID, &e.Version, &e.Action, &e.Actor, &e.ChangedAt, &e.Snapshot, &e.Diff)
+}
+
+func (r *ItemRepository) EventsSince(ctx context.Context, afterID int64, limit int) ([]models.ItemEvent, error) {
+	query := `SELECT ` + itemEventColumns + ` FROM item_revisions WHERE id > $1 AND ` + tenantScope + ` ORDER BY id LIMIT $2`
+
+	var events []models.ItemEvent
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, afterID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var e models.ItemEvent
+			if err := scanItemEvent(rows, &e); err != nil {
+				return err
+			}
+			events = append(events, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list item events: %w", err)
+	}
+
+	return events, nil
+}
+
+// Listen holds one pooled connection in LISTEN mode and passes every
+// committed revision, of every tenant, to fn in commit order. When the
+// connection drops it reconnects and replays the revisions after the last
+// one it saw; a revision with a lower id committing in between is missed.
+// It returns once ctx is done.
+func (r *ItemRepository) Listen(ctx context.Context, fn func(models.ItemEvent)) error {
+	ctx = tenant.System(ctx)
+	var lastID int64
+	for {
+		err := r.listen(ctx, &lastID, fn)
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.Warn("Item event listener failed, reconnecting", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (r *ItemRepository) listen(ctx context.Context, lastID *int64, fn func(models.ItemEvent)) error {
+	pooled, err := r.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The session stays subscribed, so it must never go back to the pool.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+itemEventsChannel); err != nil {
+		return err
+	}
+	if _, err := conn.Exec(ctx, `SELECT set_config('app.tenant_id', $1, false)`, tenant.All); err != nil {
+		return err
+	}
+
+	// Catch up on whatever was committed while we were reconnecting. Those
+	// committed after LISTEN are also notified and must not be sent twice.
+	replayed := make(map[int64]bool)
+	for *lastID > 0 {
+		events, err := r.EventsSince(ctx, *lastID, eventReplayBatch)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			fn(e)
+			replayed[e.ID] = true
+			*lastID = e.ID
+		}
+		if len(events) < eventReplayBatch {
+			break
+		}
+	}
+
+	query := `SELECT ` + itemEventColumns + ` FROM item_revisions WHERE id = $1`
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		id, err := strconv.ParseInt(n.Payload, 10, 64)
+		if err != nil {
+			slog.Warn("Ignoring malformed item event notification", "payload", n.Payload)
+			continue
+		}
+		if replayed[id] {
+			delete(replayed, id)
+			continue
+		}
+
+		var e models.ItemEvent
+		if err := scanItemEvent(conn.QueryRow(ctx, query, id), &e); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			return err
+		}
+		fn(e)
+		*lastID = max(*lastID, e.ID)
+	}
+}
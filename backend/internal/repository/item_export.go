@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// Export streams every item matching filter, in sort order, to fn as rows
+// arrive from the server, so memory use does not grow with the catalog.
+// An error from fn stops the export and is returned as is.
+func (r *ItemRepository) Export(ctx context.Context, filter *models.ItemFilter, sort []models.SortField, fn func(*models.Item) error) error {
+	where, args := buildItemFilter(filter, nil)
+	query := `
+		SELECT ` + itemColumns + `
+		FROM items` + where + buildOrderBy(sort)
+
+	var fnErr error
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var i models.Item
+		for rows.Next() {
+			i = models.Item{}
+			if err := scanItem(rows, &i); err != nil {
+				return err
+			}
+			if fnErr = fn(&i); fnErr != nil {
+				return fnErr
+			}
+		}
+		return rows.Err()
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to export items: %w", err)
+	}
+	return nil
+}
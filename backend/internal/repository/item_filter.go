@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+)
+
+// likeEscaper escapes LIKE wildcards so user input is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// buildItemFilter renders f as a WHERE clause scoped to the current
+// tenant, appending its parameters to args.
+func buildItemFilter(f *models.ItemFilter, args []interface{}) (string, []interface{}) {
+	conds := []string{tenantScope}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	switch f.Deleted {
+	case models.DeletedExclude:
+		conds = append(conds, "deleted_at IS NULL")
+	case models.DeletedOnly:
+		conds = append(conds, "deleted_at IS NOT NULL")
+	}
+
+	if f.Name != "" {
+		add("name ILIKE '%%' || $%d || '%%'", likeEscaper.Replace(f.Name))
+	}
+	if f.NamePrefix != "" {
+		add("name ILIKE $%d || '%%'", likeEscaper.Replace(f.NamePrefix))
+	}
+	if f.Currency != "" {
+		add("(currency = $%[1]d OR EXISTS (SELECT 1 FROM item_prices p WHERE p.item_id = items.id AND p.currency = $%[1]d))", f.Currency)
+	}
+	if f.MinPrice != nil {
+		add("price >= $%d", *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		add("price <= $%d", *f.MaxPrice)
+	}
+	if f.CreatedAfter != nil {
+		add("created_at >= $%d", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		add("created_at < $%d", *f.CreatedBefore)
+	}
+	if f.UpdatedAfter != nil {
+		add("updated_at >= $%d", *f.UpdatedAfter)
+	}
+	if f.UpdatedBefore != nil {
+		add("updated_at < $%d", *f.UpdatedBefore)
+	}
+
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// buildOrderBy renders an ORDER BY clause. Column names come from the
+// models.SortableColumns whitelist; created_at and id are appended as
+// tie-breakers so paging is stable.
+func buildOrderBy(sort []models.SortField) string {
+	var parts []string
+	for _, s := range effectiveSort(sort) {
+		dir := "ASC"
+		if s.Desc {
+			dir = "DESC"
+		}
+		parts = append(parts, s.Column+" "+dir)
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// effectiveSort returns the requested sort followed by the default
+// created_at DESC, id DESC tie-breakers.
+func effectiveSort(sort []models.SortField) []models.SortField {
+	out := make([]models.SortField, 0, len(sort)+2)
+	hasCreatedAt := false
+	for _, s := range sort {
+		if !models.SortableColumns[s.Column] {
+			continue
+		}
+		hasCreatedAt = hasCreatedAt || s.Column == "created_at"
+		out = append(out, s)
+	}
+	if !hasCreatedAt {
+		out = append(out, models.SortField{Column: "created_at", Desc: true})
+	}
+	return append(out, models.SortField{Column: "id", Desc: true})
+}
+
+func reverseItems(items []models.Item) {
+	for a, b := 0, len(items)-1; a < b; a, b = a+1, b-1 {
+		items[a], items[b] = items[b], items[a]
+	}
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/identity"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// Import inserts validated items with COPY, together with their price
+// lists, creation revisions and webhook deliveries, in a single
+// transaction.
+func (r *ItemRepository) Import(ctx context.Context, items []models.CreateItemDTO) (int64, error) {
+	var imported int64
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		if err := checkQuota(ctx, tx, len(items)); err != nil {
+			return err
+		}
+
+		var now time.Time
+		if err := tx.QueryRow(ctx, `SELECT NOW()`).Scan(&now); err != nil {
+			return err
+		}
+		actor := identity.Actor(ctx)
+
+		created := make([]models.Item, 0, len(items))
+		itemRows := make([][]any, 0, len(items))
+		revisionRows := make([][]any, 0, len(items))
+		var priceRows [][]any
+		for k := range items {
+			dto := &items[k]
+			i := models.Item{ID: uuid.New(), Name: dto.Name, Price: dto.Price, Currency: dto.Currency, Prices: dto.Prices, Version: 1, CreatedAt: now, UpdatedAt: now}
+			created = append(created, i)
+			snapshot := i.Snapshot()
+
+			itemRows = append(itemRows, []any{i.ID, i.Name, i.Price, i.Currency, now, now})
+			for currency, price := range dto.Prices {
+				priceRows = append(priceRows, []any{i.ID, currency, price})
+			}
+			revisionRows = append(revisionRows, []any{
+				i.ID, i.Version, models.ActionCreated, actor, now, snapshot, models.DiffSnapshots(nil, &snapshot),
+			})
+		}
+
+		var err error
+		imported, err = tx.CopyFrom(ctx, pgx.Identifier{"items"},
+			[]string{"id", "name", "price", "currency", "created_at", "updated_at"},
+			pgx.CopyFromRows(itemRows))
+		if err != nil {
+			return err
+		}
+		if len(priceRows) > 0 {
+			_, err = tx.CopyFrom(ctx, pgx.Identifier{"item_prices"},
+				[]string{"item_id", "currency", "price"},
+				pgx.CopyFromRows(priceRows))
+			if err != nil {
+				return err
+			}
+		}
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"item_revisions"},
+			[]string{"item_id", "version", "action", "actor", "changed_at", "snapshot", "diff"},
+			pgx.CopyFromRows(revisionRows))
+		if err != nil {
+			return err
+		}
+		return copyCreatedDeliveries(ctx, tx, created, now)
+	})
+	if err != nil {
+		if errors.Is(err, ErrQuotaExceeded) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("failed to import items: %w", err)
+	}
+
+	return imported, nil
+}
+
+// copyCreatedDeliveries queues an item.created delivery of every item to
+// each subscribed webhook of the tenant, as enqueueWebhooks does for
+// single creates.
+func copyCreatedDeliveries(ctx context.Context, tx pgx.Tx, items []models.Item, at time.Time) error {
+	rows, err := tx.Query(ctx, `
+		SELECT id FROM webhooks
+		WHERE active AND $1 = ANY(events) AND tenant_id = current_setting('app.tenant_id')`,
+		models.EventItemCreated)
+	if err != nil {
+		return err
+	}
+	hooks, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil || len(hooks) == 0 {
+		return err
+	}
+
+	deliveryRows, err := createdDeliveryRows(hooks, items, at)
+	if err != nil {
+		return err
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"webhook_deliveries"},
+		[]string{"webhook_id", "event_id", "event_type", "payload"},
+		pgx.CopyFromRows(deliveryRows))
+	if err != nil {
+		return fmt.Errorf("failed to queue webhooks: %w", err)
+	}
+	return nil
+}
+
+// createdDeliveryRows builds one delivery per item and webhook. Each item
+// is a single event, shared by every webhook it is sent to.
+func createdDeliveryRows(hooks []uuid.UUID, items []models.Item, at time.Time) ([][]any, error) {
+	rows := make([][]any, 0, len(hooks)*len(items))
+	for k := range items {
+		event := models.NewWebhookEvent(models.ActionCreated, &items[k], at.UTC())
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		for _, hook := range hooks {
+			rows = append(rows, []any{hook, event.ID, event.Type, payload})
+		}
+	}
+	return rows, nil
+}
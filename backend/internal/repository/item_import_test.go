@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/models"
+)
+
+func TestCreatedDeliveryRows(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	hooks := []uuid.UUID{uuid.New(), uuid.New()}
+	items := []models.Item{
+		{ID: uuid.New(), Name: "Widget", Price: models.MustParseMoney("1"), Currency: "USD", Version: 1},
+		{ID: uuid.New(), Name: "Gadget", Price: models.MustParseMoney("2"), Currency: "EUR", Version: 1},
+	}
+
+	rows, err := createdDeliveryRows(hooks, items, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(hooks)*len(items) {
+		t.Fatalf("expected %d deliveries, got %d", len(hooks)*len(items), len(rows))
+	}
+
+	events := make(map[uuid.UUID]uuid.UUID) // event id -> item id
+	for _, row := range rows {
+		if row[2] != models.EventItemCreated {
+			t.Errorf("expected %s deliveries, got %v", models.EventItemCreated, row[2])
+		}
+		var event models.WebhookEvent
+		if err := json.Unmarshal(row[3].([]byte), &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.ID != row[1] || event.Type != models.EventItemCreated || !event.CreatedAt.Equal(at) {
+			t.Errorf("unexpected event %+v in row %v", event, row)
+		}
+		if prev, ok := events[event.ID]; ok && prev != event.Data.ID {
+			t.Errorf("event %s announces both %s and %s", event.ID, prev, event.Data.ID)
+		}
+		events[event.ID] = event.Data.ID
+	}
+	if len(events) != len(items) {
+		t.Errorf("expected one event per item, got %d", len(events))
+	}
+
+	if rows, _ := createdDeliveryRows(nil, items, at); len(rows) != 0 {
+		t.Errorf("expected no deliveries without webhooks, got %d", len(rows))
+	}
+}
+
+func TestMemoryItemRepository_ImportQueuesWebhooks(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryItemRepository()
+	active := true
+	hook, _ := repo.CreateWebhook(ctx, &models.CreateWebhookDTO{URL: "https://example.test/hook", Events: []string{models.EventItemCreated}, Active: &active})
+
+	items := []models.CreateItemDTO{
+		{Name: "Widget", Price: models.MustParseMoney("1"), Currency: "USD"},
+		{Name: "Gadget", Price: models.MustParseMoney("2"), Currency: "USD"},
+	}
+	if _, err := repo.Import(ctx, items); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err := repo.Deliveries(ctx, hook.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != len(items) {
+		t.Errorf("expected a delivery per imported item, got %d", len(deliveries))
+	}
+}
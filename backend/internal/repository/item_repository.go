@@ -4,14 +4,50 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-var ErrItemNotFound = errors.New("item not found")
+var (
+	ErrItemNotFound = errors.New("item not found")
+	// ErrVersionConflict is returned when a conditional write targets an
+	// item whose version has moved on.
+	ErrVersionConflict = errors.New("item version conflict")
+	// ErrRevisionNotFound is returned when an item has no such revision.
+	ErrRevisionNotFound = errors.New("item revision not found")
+	// ErrQuotaExceeded is returned when a write would take a tenant past
+	// its item quota.
+	ErrQuotaExceeded = errors.New("tenant item quota exceeded")
+)
+
+// tenantScope restricts a query to the tenant set by withTenant. It
+// repeats the row-level security policy from migration 000010, so that
+// isolation also holds for database roles exempt from the policy.
+const tenantScope = "current_setting('app.tenant_id') IN ('*', tenant_id)"
+
+// itemColumns is the column list scanned by scanItem. The price list is
+// aggregated as text so amounts stay exact through JSON.
+const itemColumns = `id, name, price, currency,
+	COALESCE((SELECT jsonb_object_agg(p.currency, p.price::text) FROM item_prices p WHERE p.item_id = items.id), '{}'::jsonb),
+	version, created_at, updated_at, deleted_at`
+
+// scanItem scans a row selected with itemColumns. Columns selected after
+// them are scanned into extra.
+func scanItem(row pgx.Row, i *models.Item, extra ...any) error {
+	dest := append([]any{&i.ID, &i.Name, &i.Price, &i.Currency, &i.Prices, &i.Version, &i.CreatedAt, &i.UpdatedAt, &i.DeletedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	if len(i.Prices) == 0 {
+		i.Prices = nil
+	}
+	return nil
+}
 
 type ItemRepository struct {
 	db *pgxpool.Pool
@@ -21,37 +57,97 @@ func NewItemRepository(db *pgxpool.Pool) *ItemRepository {
 	return &ItemRepository{db: db}
 }
 
+// withTenant runs fn in a transaction scoped to the tenant of ctx: rows of
+// other tenants are invisible to it and new rows belong to the tenant.
+func (r *ItemRepository) withTenant(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT set_config('app.tenant_id', $1, true)`, tenant.ID(ctx)); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// checkQuota fails with ErrQuotaExceeded when n more live items would
+// take the tenant of ctx past its quota. It serialises the tenant's
+// quota-checked writes for the rest of tx so they cannot overshoot it
+// together.
+func checkQuota(ctx context.Context, tx pgx.Tx, n int) error {
+	t := tenant.FromContext(ctx)
+	if t.MaxItems <= 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('item_quota:' || $1))`, t.ID); err != nil {
+		return err
+	}
+	var count int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM items WHERE deleted_at IS NULL AND `+tenantScope).Scan(&count); err != nil {
+		return err
+	}
+	if count+n > t.MaxItems {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
 func (r *ItemRepository) Create(ctx context.Context, item *models.CreateItemDTO) (*models.Item, error) {
+	var i *models.Item
+	err := r.withTenant(ctx, func(tx pgx.Tx) (err error) {
+		i, err = createItem(ctx, tx, item)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrQuotaExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create item: %w", err)
+	}
+
+	return i, nil
+}
+
+// createItem inserts an item with its price list and first revision.
+func createItem(ctx context.Context, tx pgx.Tx, item *models.CreateItemDTO) (*models.Item, error) {
+	if err := checkQuota(ctx, tx, 1); err != nil {
+		return nil, err
+	}
+
 	query := `
-		INSERT INTO items (name, price)
-		VALUES ($1, $2)
-		RETURNING id, name, price, created_at, updated_at
+		INSERT INTO items (name, price, currency)
+		VALUES ($1, $2, $3)
+		RETURNING id
 	`
 
-	row := r.db.QueryRow(ctx, query, item.Name, item.Price)
+	var id uuid.UUID
+	if err := tx.QueryRow(ctx, query, item.Name, item.Price, item.Currency).Scan(&id); err != nil {
+		return nil, err
+	}
+	if err := replacePrices(ctx, tx, id, item.Prices); err != nil {
+		return nil, err
+	}
 
 	var i models.Item
-	err := row.Scan(&i.ID, &i.Name, &i.Price, &i.CreatedAt, &i.UpdatedAt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create item: %w", err)
+	if err := scanItem(tx.QueryRow(ctx, `SELECT `+itemColumns+` FROM items WHERE id = $1`, id), &i); err != nil {
+		return nil, err
+	}
+	if err := recordRevision(ctx, tx, models.ActionCreated, nil, &i); err != nil {
+		return nil, err
 	}
-
 	return &i, nil
 }
 
 func (r *ItemRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Item, error) {
 	query := `
-		SELECT id, name, price, created_at, updated_at
+		SELECT ` + itemColumns + `
 		FROM items
-		WHERE id = $1
-	`
-
-	row := r.db.QueryRow(ctx, query, id)
+		WHERE id = $1 AND deleted_at IS NULL AND ` + tenantScope
 
 	var i models.Item
-	err := row.Scan(&i.ID, &i.Name, &i.Price, &i.CreatedAt, &i.UpdatedAt)
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		return scanItem(tx.QueryRow(ctx, query, id), &i)
+	})
 	if err != nil {
-		if err == pgx.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrItemNotFound
 		}
 		return nil, fmt.Errorf("failed to get item: %w", err)
@@ -60,41 +156,158 @@ func (r *ItemRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Ite
 	return &i, nil
 }
 
-func (r *ItemRepository) GetAll(ctx context.Context, limit, offset int) ([]models.Item, int, error) {
-	countQuery := `SELECT COUNT(*) FROM items`
-	var total int
-	if err := r.db.QueryRow(ctx, countQuery).Scan(&total); err != nil {
-		return nil, 0, fmt.Errorf("failed to count items: %w", err)
-	}
+func (r *ItemRepository) GetAll(ctx context.Context, q *models.ItemListQuery) ([]models.Item, int, error) {
+	where, args := buildItemFilter(&q.Filter, nil)
 
+	countQuery := `SELECT COUNT(*) FROM items` + where
 	query := `
-		SELECT id, name, price, created_at, updated_at
-		FROM items
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
+		SELECT ` + itemColumns + `
+		FROM items` + where + buildOrderBy(q.Sort) +
+		fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+
+	var (
+		total int
+		items []models.Item
+	)
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+			return fmt.Errorf("failed to count items: %w", err)
+		}
+
+		rows, err := tx.Query(ctx, query, append(args, q.Limit, q.Offset)...)
+		if err != nil {
+			return fmt.Errorf("failed to list items: %w", err)
+		}
+		defer rows.Close()
 
-	rows, err := r.db.Query(ctx, query, limit, offset)
+		for rows.Next() {
+			var i models.Item
+			if err := scanItem(rows, &i); err != nil {
+				return fmt.Errorf("failed to scan item: %w", err)
+			}
+			items = append(items, i)
+		}
+		return rows.Err()
+	})
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to list items: %w", err)
+		return nil, 0, err
+	}
+
+	return items, total, nil
+}
+
+func (r *ItemRepository) GetPage(ctx context.Context, filter *models.ItemFilter, cursor *models.Cursor, limit int) ([]models.Item, bool, error) {
+	where, args := buildItemFilter(filter, nil)
+
+	order := " ORDER BY created_at DESC, id DESC"
+	if cursor != nil {
+		op := "<"
+		if cursor.Backward {
+			op = ">"
+			order = " ORDER BY created_at ASC, id ASC"
+		}
+		where += fmt.Sprintf(" AND (created_at, id) %s ($%d, $%d)", op, len(args)+1, len(args)+2)
+		args = append(args, cursor.CreatedAt, cursor.ID)
 	}
-	defer rows.Close()
+
+	// Fetch one extra row to learn whether another page exists.
+	query := `
+		SELECT ` + itemColumns + `
+		FROM items` + where + order + fmt.Sprintf(" LIMIT $%d", len(args)+1)
+	args = append(args, limit+1)
 
 	var items []models.Item
-	for rows.Next() {
-		var i models.Item
-		if err := rows.Scan(&i.ID, &i.Name, &i.Price, &i.CreatedAt, &i.UpdatedAt); err != nil {
-			return nil, 0, fmt.Errorf("failed to scan item: %w", err)
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var i models.Item
+			if err := scanItem(rows, &i); err != nil {
+				return err
+			}
+			items = append(items, i)
 		}
-		items = append(items, i)
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list items: %w", err)
 	}
 
-	return items, total, nil
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	if cursor != nil && cursor.Backward {
+		reverseItems(items)
+	}
+
+	return items, hasMore, nil
 }
 
-func (r *ItemRepository) Update(ctx context.Context, id uuid.UUID, updates *models.UpdateItemDTO) (*models.Item, error) {
+// Update applies updates and bumps the item version. A non-zero
+// expectedVersion makes the write conditional on the current version.
+func (r *ItemRepository) Update(ctx context.Context, id uuid.UUID, updates *models.UpdateItemDTO, expectedVersion int64) (*models.Item, error) {
+	var after *models.Item
+	err := r.withTenant(ctx, func(tx pgx.Tx) (err error) {
+		after, err = updateItem(ctx, tx, id, updates, expectedVersion)
+		return err
+	})
+	if err != nil {
+		var invalid models.ValidationErrors
+		if errors.Is(err, ErrItemNotFound) || errors.Is(err, ErrVersionConflict) || errors.As(err, &invalid) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update item: %w", err)
+	}
+
+	return after, nil
+}
+
+func updateItem(ctx context.Context, tx pgx.Tx, id uuid.UUID, updates *models.UpdateItemDTO, expectedVersion int64) (*models.Item, error) {
+	before, err := lockItem(ctx, tx, id, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := updates.CheckPrices(before.Currency); err != nil {
+		return nil, err
+	}
+	after, err := applyUpdate(ctx, tx, id, updates)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordRevision(ctx, tx, models.ActionUpdated, before, after); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// lockItem loads a live item and locks its row for the rest of tx,
+// checking expectedVersion unless it is zero.
+func lockItem(ctx context.Context, tx pgx.Tx, id uuid.UUID, expectedVersion int64) (*models.Item, error) {
+	query := `SELECT ` + itemColumns + ` FROM items WHERE id = $1 AND deleted_at IS NULL AND ` + tenantScope + ` FOR UPDATE`
+
+	var i models.Item
+	if err := scanItem(tx.QueryRow(ctx, query, id), &i); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrItemNotFound
+		}
+		return nil, err
+	}
+	if expectedVersion != 0 && i.Version != expectedVersion {
+		return nil, ErrVersionConflict
+	}
+	return &i, nil
+}
+
+// applyUpdate writes updates to a row already locked by lockItem and
+// returns the new state.
+func applyUpdate(ctx context.Context, tx pgx.Tx, id uuid.UUID, updates *models.UpdateItemDTO) (*models.Item, error) {
 	// Build dynamic update query
-	query := "UPDATE items SET updated_at = NOW()"
+	query := "UPDATE items SET updated_at = NOW(), version = version + 1"
 	args := []interface{}{}
 	argId := 1
 
@@ -108,32 +321,128 @@ func (r *ItemRepository) Update(ctx context.Context, id uuid.UUID, updates *mode
 		args = append(args, *updates.Price)
 		argId++
 	}
+	if updates.Currency != nil {
+		query += fmt.Sprintf(", currency = $%d", argId)
+		args = append(args, *updates.Currency)
+		argId++
+	}
 
-	query += fmt.Sprintf(" WHERE id = $%d RETURNING id, name, price, created_at, updated_at", argId)
+	query += fmt.Sprintf(" WHERE id = $%d", argId)
 	args = append(args, id)
 
-	row := r.db.QueryRow(ctx, query, args...)
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return nil, err
+	}
+	if updates.Prices != nil {
+		if err := replacePrices(ctx, tx, id, *updates.Prices); err != nil {
+			return nil, err
+		}
+	}
+	if updates.Currency != nil {
+		// The base currency cannot also appear in the price list.
+		_, err := tx.Exec(ctx, `DELETE FROM item_prices WHERE item_id = $1 AND currency = $2`, id, *updates.Currency)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	var i models.Item
-	err := row.Scan(&i.ID, &i.Name, &i.Price, &i.CreatedAt, &i.UpdatedAt)
+	if err := scanItem(tx.QueryRow(ctx, `SELECT `+itemColumns+` FROM items WHERE id = $1`, id), &i); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// replacePrices swaps the price list of an item for prices.
+func replacePrices(ctx context.Context, tx pgx.Tx, id uuid.UUID, prices map[string]models.Money) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM item_prices WHERE item_id = $1`, id); err != nil {
+		return err
+	}
+	for currency, price := range prices {
+		_, err := tx.Exec(ctx, `INSERT INTO item_prices (item_id, currency, price) VALUES ($1, $2, $3)`, id, currency, price)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete moves an item to the trash. A non-zero expectedVersion makes the
+// delete conditional on the current version.
+func (r *ItemRepository) Delete(ctx context.Context, id uuid.UUID, expectedVersion int64) error {
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		return deleteItem(ctx, tx, id, expectedVersion)
+	})
+	if err != nil {
+		if errors.Is(err, ErrItemNotFound) || errors.Is(err, ErrVersionConflict) {
+			return err
+		}
+		return fmt.Errorf("failed to delete item: %w", err)
+	}
+	return nil
+}
+
+func deleteItem(ctx context.Context, tx pgx.Tx, id uuid.UUID, expectedVersion int64) error {
+	before, err := lockItem(ctx, tx, id, expectedVersion)
 	if err != nil {
-		if err == pgx.ErrNoRows {
+		return err
+	}
+
+	query := `
+		UPDATE items SET deleted_at = NOW(), version = version + 1
+		WHERE id = $1
+		RETURNING ` + itemColumns
+
+	var after models.Item
+	if err := scanItem(tx.QueryRow(ctx, query, id), &after); err != nil {
+		return err
+	}
+	return recordRevision(ctx, tx, models.ActionDeleted, before, &after)
+}
+
+// Restore takes an item back out of the trash.
+func (r *ItemRepository) Restore(ctx context.Context, id uuid.UUID) (*models.Item, error) {
+	var i models.Item
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		if err := checkQuota(ctx, tx, 1); err != nil {
+			return err
+		}
+
+		query := `
+			UPDATE items SET deleted_at = NULL, version = version + 1
+			WHERE id = $1 AND deleted_at IS NOT NULL AND ` + tenantScope + `
+			RETURNING ` + itemColumns
+
+		if err := scanItem(tx.QueryRow(ctx, query, id), &i); err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, models.ActionRestored, &i, &i)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrItemNotFound
 		}
-		return nil, fmt.Errorf("failed to update item: %w", err)
+		if errors.Is(err, ErrQuotaExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to restore item: %w", err)
 	}
 
 	return &i, nil
 }
 
-func (r *ItemRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM items WHERE id = $1`
-	commandTag, err := r.db.Exec(ctx, query, id)
+// Purge permanently removes items of the tenant of ctx, or of every
+// tenant for tenant.All, that were trashed before cutoff. Their revisions
+// are kept so the history stays auditable.
+func (r *ItemRepository) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	var purged int64
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, `DELETE FROM items WHERE deleted_at < $1 AND `+tenantScope, cutoff)
+		purged = commandTag.RowsAffected()
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to delete item: %w", err)
-	}
-	if commandTag.RowsAffected() == 0 {
-		return ErrItemNotFound
+		return 0, fmt.Errorf("failed to purge items: %w", err)
 	}
-	return nil
+	return purged, nil
 }
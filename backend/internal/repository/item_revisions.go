@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/identity"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// recordRevision appends the revision produced by a write to the item's
+// history and queues the matching webhook deliveries. before is nil for
+// creations.
+func recordRevision(ctx context.Context, tx pgx.Tx, action string, before, after *models.Item) error {
+	snapshot := after.Snapshot()
+	var prev *models.ItemSnapshot
+	if before != nil {
+		s := before.Snapshot()
+		prev = &s
+	}
+	diff := models.DiffSnapshots(prev, &snapshot)
+	if diff == nil {
+		diff = map[string]models.FieldChange{}
+	}
+
+	query := `
+		INSERT INTO item_revisions (item_id, version, action, actor, changed_at, snapshot, diff)
+		VALUES ($1, $2, $3, $4, NOW(), $5, $6)
+	`
+	_, err := tx.Exec(ctx, query, after.ID, after.Version, action, identity.Actor(ctx), snapshot, diff)
+	if err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return enqueueWebhooks(ctx, tx, action, after)
+}
+
+func (r *ItemRepository) History(ctx context.Context, id uuid.UUID) ([]models.ItemRevision, error) {
+	query := `
+		SELECT item_id, version, action, actor, changed_at, snapshot, diff
+		FROM item_revisions
+		WHERE item_id = $1 AND ` + tenantScope + `
+		ORDER BY version DESC
+	`
+
+	var revisions []models.ItemRevision
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var rev models.ItemRevision
+			if err := rows.Scan(&rev.ItemID, &rev.Version, &rev.Action, &rev.Actor, &rev.ChangedAt, &rev.Snapshot, &rev.Diff); err != nil {
+				return err
+			}
+			revisions = append(revisions, rev)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item history: %w", err)
+	}
+	if len(revisions) == 0 {
+		return nil, ErrItemNotFound
+	}
+
+	return revisions, nil
+}
+
+func (r *ItemRepository) GetAsOf(ctx context.Context, id uuid.UUID, at time.Time) (*models.Item, error) {
+	query := `
+		SELECT r.version, r.action, r.changed_at, r.snapshot,
+			(SELECT MIN(c.changed_at) FROM item_revisions c WHERE c.item_id = r.item_id)
+		FROM item_revisions r
+		WHERE r.item_id = $1 AND r.changed_at <= $2 AND ` + tenantScope + `
+		ORDER BY r.version DESC
+		LIMIT 1
+	`
+
+	var (
+		rev       models.ItemRevision
+		createdAt time.Time
+	)
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, id, at).Scan(&rev.Version, &rev.Action, &rev.ChangedAt, &rev.Snapshot, &createdAt)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrItemNotFound
+		}
+		return nil, fmt.Errorf("failed to get item revision: %w", err)
+	}
+	if rev.Action == models.ActionDeleted {
+		return nil, ErrItemNotFound
+	}
+
+	rev.ItemID = id
+	return itemFromRevision(&rev, createdAt), nil
+}
+
+// Revert restores the editable fields of a live item to those recorded at
+// version, as a new revision.
+func (r *ItemRepository) Revert(ctx context.Context, id uuid.UUID, version, expectedVersion int64) (*models.Item, error) {
+	var after *models.Item
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		before, err := lockItem(ctx, tx, id, expectedVersion)
+		if err != nil {
+			return err
+		}
+
+		var snapshot models.ItemSnapshot
+		query := `SELECT snapshot FROM item_revisions WHERE item_id = $1 AND version = $2`
+		if err := tx.QueryRow(ctx, query, id, version).Scan(&snapshot); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRevisionNotFound
+			}
+			return err
+		}
+
+		if after, err = applyUpdate(ctx, tx, id, snapshotUpdate(&snapshot)); err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, models.ActionReverted, before, after)
+	})
+	if err != nil {
+		if errors.Is(err, ErrItemNotFound) || errors.Is(err, ErrVersionConflict) || errors.Is(err, ErrRevisionNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to revert item: %w", err)
+	}
+
+	return after, nil
+}
+
+// snapshotUpdate builds the update that brings an item back to snapshot.
+func snapshotUpdate(s *models.ItemSnapshot) *models.UpdateItemDTO {
+	prices := s.Prices
+	if prices == nil {
+		prices = map[string]models.Money{}
+	}
+	return &models.UpdateItemDTO{
+		Name:     &s.Name,
+		Price:    &s.Price,
+		Currency: &s.Currency,
+		Prices:   &prices,
+	}
+}
+
+// itemFromRevision rebuilds the item as it looked at rev.
+func itemFromRevision(rev *models.ItemRevision, createdAt time.Time) *models.Item {
+	return &models.Item{
+		ID:        rev.ItemID,
+		Name:      rev.Snapshot.Name,
+		Price:     rev.Snapshot.Price,
+		Currency:  rev.Snapshot.Currency,
+		Prices:    rev.Snapshot.Prices,
+		Version:   rev.Version,
+		CreatedAt: createdAt,
+		UpdatedAt: rev.ChangedAt,
+	}
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// wordSimilarityThreshold is the trigram word similarity above which a
+// name counts as a fuzzy match. pg_trgm defaults to 0.6, which rejects
+// a single typo in most short words.
+const wordSimilarityThreshold = 0.3
+
+// headlineOptions makes ts_headline return the whole name with every
+// matched word delimited, for models.RenderHighlight.
+const headlineOptions = "StartSel=" + models.MatchStart + ", StopSel=" + models.MatchStop + ", HighlightAll=true"
+
+// Search ranks items whose name matches q.Text either as a full-text query
+// or, to tolerate typos, by trigram word similarity. Both predicates are
+// served by the GIN indexes from migration 000006; the threshold of the
+// <% operator is raised for the search transaction only.
+func (r *ItemRepository) Search(ctx context.Context, q *models.ItemSearchQuery) ([]models.ItemSearchResult, error) {
+	where, args := buildItemFilter(&q.Filter, []interface{}{q.Text, headlineOptions})
+	where += " AND (search_vector @@ query OR $1 <% name)"
+
+	query := `
+		SELECT ` + itemColumns + `,
+			(ts_rank(search_vector, query) + word_similarity($1, name))::float8 AS rank,
+			ts_headline('simple', name, query, $2)
+		FROM items, websearch_to_tsquery('simple', $1) AS query` + where + `
+		ORDER BY rank DESC, created_at DESC, id DESC` +
+		fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, q.Limit, q.Offset)
+
+	var results []models.ItemSearchResult
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		threshold := strconv.FormatFloat(wordSimilarityThreshold, 'f', -1, 64)
+		if _, err := tx.Exec(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`, threshold); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var res models.ItemSearchResult
+			if err := scanItem(rows, &res.Item, &res.Rank, &res.Highlight); err != nil {
+				return err
+			}
+			res.Highlight = models.RenderHighlight(res.Highlight)
+			results = append(results, res)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search items: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *ItemRepository) ItemStats(ctx context.Context) (*models.ItemStats, error) {
+	query := `
+		SELECT
+			(SELECT count(*) FROM items WHERE deleted_at IS NULL AND ` + tenantScope + `),
+			(SELECT count(*) FROM items WHERE deleted_at IS NOT NULL AND ` + tenantScope + `),
+			count(*) FILTER (WHERE action = 'created'),
+			count(*) FILTER (WHERE action = 'deleted')
+		FROM item_revisions
+		WHERE changed_at > NOW() - interval '1 minute' AND ` + tenantScope
+
+	var s models.ItemStats
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query).Scan(&s.Live, &s.Trashed, &s.CreatedLastMinute, &s.DeletedLastMinute)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute item stats: %w", err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+)
+
+func (r *MemoryItemRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := *key
+	k.ID = uuid.New()
+	k.Tenant = tenant.ID(ctx)
+	k.Hash = slices.Clone(key.Hash)
+	k.Scopes = slices.Clone(key.Scopes)
+	k.LastUsedAt = nil
+	k.CreatedAt = r.timestamp()
+	r.apiKeys[k.ID] = k
+	return &k, nil
+}
+
+func (r *MemoryItemRepository) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]models.APIKey, 0, len(r.apiKeys))
+	for _, k := range r.apiKeys {
+		if inTenant(ctx, k.Tenant) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if c := keys[a].CreatedAt.Compare(keys[b].CreatedAt); c != 0 {
+			return c < 0
+		}
+		return keys[a].ID.String() < keys[b].ID.String()
+	})
+	return keys, nil
+}
+
+func (r *MemoryItemRepository) DeleteAPIKey(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if k, ok := r.apiKeys[id]; !ok || !inTenant(ctx, k.Tenant) {
+		return ErrAPIKeyNotFound
+	}
+	delete(r.apiKeys, id)
+	return nil
+}
+
+func (r *MemoryItemRepository) APIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, k := range r.apiKeys {
+		if k.Prefix == prefix && inTenant(ctx, k.Tenant) {
+			return &k, nil
+		}
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+func (r *MemoryItemRepository) TouchAPIKey(ctx context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.apiKeys[id]
+	if !ok || (k.LastUsedAt != nil && at.Sub(*k.LastUsedAt) < apiKeyTouchInterval) {
+		return nil
+	}
+	at = at.UTC().Truncate(time.Microsecond)
+	k.LastUsedAt = &at
+	r.apiKeys[id] = k
+	return nil
+}
@@ -0,0 +1,801 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/identity"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+)
+
+// MemoryItemRepository is an in-process ItemStore used for tests and the
+// `-store=memory` demo mode. It mirrors the Postgres repository's ordering,
+// not-found semantics and timestamp behaviour.
+type MemoryItemRepository struct {
+	mu        sync.RWMutex
+	items     map[uuid.UUID]models.Item
+	revisions map[uuid.UUID][]models.ItemRevision
+	// owners maps the id of every item and webhook to its tenant.
+	owners map[uuid.UUID]string
+	// events is the change feed: every revision in the order it was made.
+	events       []models.ItemEvent
+	listeners    map[int]func(models.ItemEvent)
+	nextListener int
+	webhooks     map[uuid.UUID]models.Webhook
+	deliveries   map[int64]models.WebhookDelivery
+	lastDelivery int64
+	apiKeys      map[uuid.UUID]models.APIKey
+	now          func() time.Time
+}
+
+func NewMemoryItemRepository() *MemoryItemRepository {
+	return &MemoryItemRepository{
+		items:      make(map[uuid.UUID]models.Item),
+		revisions:  make(map[uuid.UUID][]models.ItemRevision),
+		owners:     make(map[uuid.UUID]string),
+		listeners:  make(map[int]func(models.ItemEvent)),
+		webhooks:   make(map[uuid.UUID]models.Webhook),
+		deliveries: make(map[int64]models.WebhookDelivery),
+		apiKeys:    make(map[uuid.UUID]models.APIKey),
+		now:        time.Now,
+	}
+}
+
+// timestamp returns the current time at Postgres TIMESTAMPTZ precision.
+func (r *MemoryItemRepository) timestamp() time.Time {
+	return r.now().UTC().Truncate(time.Microsecond)
+}
+
+// visible reports whether the item or webhook id belongs to the tenant of
+// ctx, mirroring tenantScope. Callers must hold r.mu.
+func (r *MemoryItemRepository) visible(ctx context.Context, id uuid.UUID) bool {
+	return inTenant(ctx, r.owners[id])
+}
+
+// inTenant reports whether a row of tenant owner is in scope for ctx.
+func inTenant(ctx context.Context, owner string) bool {
+	t := tenant.ID(ctx)
+	return t == tenant.All || owner == t
+}
+
+// checkQuota fails with ErrQuotaExceeded when n more live items would
+// take the tenant of ctx past its quota. Callers must hold r.mu.
+func (r *MemoryItemRepository) checkQuota(ctx context.Context, n int) error {
+	t := tenant.FromContext(ctx)
+	if t.MaxItems <= 0 {
+		return nil
+	}
+	live := 0
+	for id, i := range r.items {
+		if i.DeletedAt == nil && r.owners[id] == t.ID {
+			live++
+		}
+	}
+	if live+n > t.MaxItems {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+func (r *MemoryItemRepository) Create(ctx context.Context, item *models.CreateItemDTO) (*models.Item, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.publish(len(r.events))
+
+	if err := r.checkQuota(ctx, 1); err != nil {
+		return nil, err
+	}
+	return r.create(ctx, item), nil
+}
+
+// create stores a new item in the tenant of ctx. Callers must hold r.mu
+// for writing.
+func (r *MemoryItemRepository) create(ctx context.Context, item *models.CreateItemDTO) *models.Item {
+	now := r.timestamp()
+	i := models.Item{
+		ID:        uuid.New(),
+		Name:      item.Name,
+		Price:     item.Price,
+		Currency:  item.Currency,
+		Prices:    clonePrices(item.Prices),
+		Version:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	r.items[i.ID] = i
+	r.owners[i.ID] = tenant.ID(ctx)
+	r.record(ctx, models.ActionCreated, nil, &i, now)
+
+	return &i
+}
+
+func (r *MemoryItemRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Item, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i, ok := r.items[id]
+	if !ok || i.DeletedAt != nil || !r.visible(ctx, id) {
+		return nil, ErrItemNotFound
+	}
+
+	return &i, nil
+}
+
+func (r *MemoryItemRepository) GetAll(ctx context.Context, q *models.ItemListQuery) ([]models.Item, int, error) {
+	r.mu.RLock()
+	all := make([]models.Item, 0, len(r.items))
+	for id, i := range r.items {
+		if r.visible(ctx, id) && matchesFilter(&i, &q.Filter) {
+			all = append(all, i)
+		}
+	}
+	r.mu.RUnlock()
+
+	sortItems(all, q.Sort)
+
+	total := len(all)
+	if q.Offset >= total {
+		return nil, total, nil
+	}
+	end := q.Offset + q.Limit
+	if end > total {
+		end = total
+	}
+
+	return all[q.Offset:end], total, nil
+}
+
+func (r *MemoryItemRepository) Export(ctx context.Context, filter *models.ItemFilter, sort []models.SortField, fn func(*models.Item) error) error {
+	r.mu.RLock()
+	all := make([]models.Item, 0, len(r.items))
+	for id, i := range r.items {
+		if r.visible(ctx, id) && matchesFilter(&i, filter) {
+			all = append(all, i)
+		}
+	}
+	r.mu.RUnlock()
+
+	sortItems(all, sort)
+
+	for n := range all {
+		if err := fn(&all[n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search mirrors the Postgres ranking: items containing every query word
+// come first, and the rest match when a close spelling of the query
+// appears in their name.
+func (r *MemoryItemRepository) Search(ctx context.Context, q *models.ItemSearchQuery) ([]models.ItemSearchResult, error) {
+	terms := make(map[string]bool)
+	for _, w := range searchWords(q.Text) {
+		terms[w] = true
+	}
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	r.mu.RLock()
+	var results []models.ItemSearchResult
+	for id, i := range r.items {
+		if !r.visible(ctx, id) || !matchesFilter(&i, &q.Filter) {
+			continue
+		}
+		words := searchWords(i.Name)
+		found := 0
+		for t := range terms {
+			if slices.Contains(words, t) {
+				found++
+			}
+		}
+		similarity := wordSimilarity(terms, words)
+		if found < len(terms) && similarity < wordSimilarityThreshold {
+			continue
+		}
+		results = append(results, models.ItemSearchResult{
+			Item:      i,
+			Rank:      float64(found)/float64(len(terms)) + similarity,
+			Highlight: highlightWords(i.Name, terms),
+		})
+	}
+	r.mu.RUnlock()
+
+	sort.SliceStable(results, func(a, b int) bool {
+		if results[a].Rank != results[b].Rank {
+			return results[a].Rank > results[b].Rank
+		}
+		if c := results[a].CreatedAt.Compare(results[b].CreatedAt); c != 0 {
+			return c > 0
+		}
+		return results[a].ID.String() > results[b].ID.String()
+	})
+
+	if q.Offset >= len(results) {
+		return nil, nil
+	}
+	results = results[q.Offset:]
+	if len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results, nil
+}
+
+func (r *MemoryItemRepository) GetPage(ctx context.Context, filter *models.ItemFilter, cursor *models.Cursor, limit int) ([]models.Item, bool, error) {
+	r.mu.RLock()
+	all := make([]models.Item, 0, len(r.items))
+	for id, i := range r.items {
+		if r.visible(ctx, id) && matchesFilter(&i, filter) {
+			all = append(all, i)
+		}
+	}
+	r.mu.RUnlock()
+
+	sortItems(all, nil)
+
+	if cursor == nil {
+		if len(all) > limit {
+			return all[:limit], true, nil
+		}
+		return all, false, nil
+	}
+
+	// Position of the first item strictly after the cursor in keyset order.
+	pos := sort.Search(len(all), func(n int) bool {
+		c := all[n].CreatedAt.Compare(cursor.CreatedAt)
+		if c == 0 {
+			c = strings.Compare(all[n].ID.String(), cursor.ID.String())
+		}
+		return c < 0
+	})
+
+	if !cursor.Backward {
+		end := pos + limit
+		if end >= len(all) {
+			return all[pos:], false, nil
+		}
+		return all[pos:end], true, nil
+	}
+
+	// Backward: the items newer than the cursor end just before it.
+	end := pos
+	if end > 0 && keysetEqual(&all[end-1], cursor) {
+		end--
+	}
+	start := end - limit
+	if start <= 0 {
+		return all[:end], false, nil
+	}
+	return all[start:end], true, nil
+}
+
+func keysetEqual(i *models.Item, c *models.Cursor) bool {
+	return i.CreatedAt.Equal(c.CreatedAt) && i.ID == c.ID
+}
+
+func (r *MemoryItemRepository) Update(ctx context.Context, id uuid.UUID, updates *models.UpdateItemDTO, expectedVersion int64) (*models.Item, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.publish(len(r.events))
+
+	return r.update(ctx, id, updates, expectedVersion)
+}
+
+// update applies updates to a live item. Callers must hold r.mu for
+// writing.
+func (r *MemoryItemRepository) update(ctx context.Context, id uuid.UUID, updates *models.UpdateItemDTO, expectedVersion int64) (*models.Item, error) {
+	before, err := r.liveItem(ctx, id, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := updates.CheckPrices(before.Currency); err != nil {
+		return nil, err
+	}
+
+	i := r.applyUpdate(before, updates)
+	r.record(ctx, models.ActionUpdated, &before, &i, i.UpdatedAt)
+
+	return &i, nil
+}
+
+// liveItem returns a non-trashed item of the tenant of ctx, checking
+// expectedVersion unless it is zero. Callers must hold r.mu.
+func (r *MemoryItemRepository) liveItem(ctx context.Context, id uuid.UUID, expectedVersion int64) (models.Item, error) {
+	i, ok := r.items[id]
+	if !ok || i.DeletedAt != nil || !r.visible(ctx, id) {
+		return models.Item{}, ErrItemNotFound
+	}
+	if expectedVersion != 0 && i.Version != expectedVersion {
+		return models.Item{}, ErrVersionConflict
+	}
+	return i, nil
+}
+
+// applyUpdate stores the result of applying updates to i. Callers must
+// hold r.mu for writing.
+func (r *MemoryItemRepository) applyUpdate(i models.Item, updates *models.UpdateItemDTO) models.Item {
+	if updates.Name != nil {
+		i.Name = *updates.Name
+	}
+	if updates.Price != nil {
+		i.Price = *updates.Price
+	}
+	if updates.Prices != nil {
+		i.Prices = clonePrices(*updates.Prices)
+	}
+	if updates.Currency != nil {
+		i.Currency = *updates.Currency
+		if _, ok := i.Prices[i.Currency]; ok {
+			i.Prices = clonePrices(i.Prices)
+			delete(i.Prices, i.Currency)
+		}
+	}
+	i.Version++
+	i.UpdatedAt = r.timestamp()
+	r.items[i.ID] = i
+	return i
+}
+
+func (r *MemoryItemRepository) Delete(ctx context.Context, id uuid.UUID, expectedVersion int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.publish(len(r.events))
+
+	return r.delete(ctx, id, expectedVersion)
+}
+
+// delete moves a live item to the trash. Callers must hold r.mu for
+// writing.
+func (r *MemoryItemRepository) delete(ctx context.Context, id uuid.UUID, expectedVersion int64) error {
+	before, err := r.liveItem(ctx, id, expectedVersion)
+	if err != nil {
+		return err
+	}
+
+	i := before
+	now := r.timestamp()
+	i.DeletedAt = &now
+	i.Version++
+	r.items[id] = i
+	r.record(ctx, models.ActionDeleted, &before, &i, now)
+
+	return nil
+}
+
+func (r *MemoryItemRepository) Restore(ctx context.Context, id uuid.UUID) (*models.Item, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.publish(len(r.events))
+
+	i, ok := r.items[id]
+	if !ok || i.DeletedAt == nil || !r.visible(ctx, id) {
+		return nil, ErrItemNotFound
+	}
+	if err := r.checkQuota(ctx, 1); err != nil {
+		return nil, err
+	}
+
+	i.DeletedAt = nil
+	i.Version++
+	r.items[id] = i
+	r.record(ctx, models.ActionRestored, &i, &i, r.timestamp())
+
+	return &i, nil
+}
+
+func (r *MemoryItemRepository) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for id, i := range r.items {
+		if i.DeletedAt != nil && i.DeletedAt.Before(cutoff) && r.visible(ctx, id) {
+			delete(r.items, id)
+			delete(r.owners, id)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+func (r *MemoryItemRepository) History(ctx context.Context, id uuid.UUID) ([]models.ItemRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	revs := r.revisions[id]
+	if len(revs) == 0 || !r.visible(ctx, id) {
+		return nil, ErrItemNotFound
+	}
+
+	out := make([]models.ItemRevision, len(revs))
+	for n, rev := range revs {
+		out[len(revs)-1-n] = rev
+	}
+	return out, nil
+}
+
+func (r *MemoryItemRepository) GetAsOf(ctx context.Context, id uuid.UUID, at time.Time) (*models.Item, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.visible(ctx, id) {
+		return nil, ErrItemNotFound
+	}
+	revs := r.revisions[id]
+	for n := len(revs) - 1; n >= 0; n-- {
+		if revs[n].ChangedAt.After(at) {
+			continue
+		}
+		if revs[n].Action == models.ActionDeleted {
+			return nil, ErrItemNotFound
+		}
+		return itemFromRevision(&revs[n], revs[0].ChangedAt), nil
+	}
+	return nil, ErrItemNotFound
+}
+
+func (r *MemoryItemRepository) Revert(ctx context.Context, id uuid.UUID, version, expectedVersion int64) (*models.Item, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.publish(len(r.events))
+
+	before, err := r.liveItem(ctx, id, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot *models.ItemSnapshot
+	for n := range r.revisions[id] {
+		if r.revisions[id][n].Version == version {
+			snapshot = &r.revisions[id][n].Snapshot
+			break
+		}
+	}
+	if snapshot == nil {
+		return nil, ErrRevisionNotFound
+	}
+
+	i := r.applyUpdate(before, snapshotUpdate(snapshot))
+	r.record(ctx, models.ActionReverted, &before, &i, i.UpdatedAt)
+
+	return &i, nil
+}
+
+func (r *MemoryItemRepository) Bulk(ctx context.Context, ops []models.BulkOperation, atomic bool) ([]BulkOutcome, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.publish(len(r.events))
+
+	// Snapshot the maps so an atomic batch can be rolled back. Revision
+	// slices are only ever appended to, so a shallow copy is enough.
+	var items map[uuid.UUID]models.Item
+	var revisions map[uuid.UUID][]models.ItemRevision
+	var owners map[uuid.UUID]string
+	var deliveries map[int64]models.WebhookDelivery
+	if atomic {
+		items, revisions, owners = maps.Clone(r.items), maps.Clone(r.revisions), maps.Clone(r.owners)
+		deliveries = maps.Clone(r.deliveries)
+	}
+	events := len(r.events)
+
+	outcomes := make([]BulkOutcome, len(ops))
+	for n := range ops {
+		op := &ops[n]
+		var item *models.Item
+		var err error
+		switch op.Op {
+		case models.BulkCreate:
+			if err = r.checkQuota(ctx, 1); err == nil {
+				item = r.create(ctx, op.Create)
+			}
+		case models.BulkUpdate:
+			item, err = r.update(ctx, op.ID, op.Update, op.Version)
+		case models.BulkDelete:
+			err = r.delete(ctx, op.ID, op.Version)
+		default:
+			err = fmt.Errorf("unknown bulk operation %q", op.Op)
+		}
+
+		if err != nil && atomic {
+			r.items, r.revisions, r.events = items, revisions, r.events[:events]
+			r.owners, r.deliveries = owners, deliveries
+			return nil, &BulkError{Index: n, Err: err}
+		}
+		outcomes[n] = BulkOutcome{Item: item, Err: err}
+	}
+
+	return outcomes, nil
+}
+
+func (r *MemoryItemRepository) Import(ctx context.Context, items []models.CreateItemDTO) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.publish(len(r.events))
+
+	if err := r.checkQuota(ctx, len(items)); err != nil {
+		return 0, err
+	}
+	for k := range items {
+		r.create(ctx, &items[k])
+	}
+	return int64(len(items)), nil
+}
+
+func (r *MemoryItemRepository) ItemStats(ctx context.Context) (*models.ItemStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var s models.ItemStats
+	for id, i := range r.items {
+		if !r.visible(ctx, id) {
+			continue
+		}
+		if i.DeletedAt == nil {
+			s.Live++
+		} else {
+			s.Trashed++
+		}
+	}
+	since := r.now().Add(-time.Minute)
+	for n := len(r.events) - 1; n >= 0 && r.events[n].ChangedAt.After(since); n-- {
+		if !inTenant(ctx, r.events[n].Tenant) {
+			continue
+		}
+		switch r.events[n].Action {
+		case models.ActionCreated:
+			s.CreatedLastMinute++
+		case models.ActionDeleted:
+			s.DeletedLastMinute++
+		}
+	}
+	return &s, nil
+}
+
+// record appends a revision for a write by the tenant of ctx and queues
+// its webhook deliveries. Callers must hold r.mu.
+func (r *MemoryItemRepository) record(ctx context.Context, action string, before, after *models.Item, at time.Time) {
+	snapshot := after.Snapshot()
+	var prev *models.ItemSnapshot
+	if before != nil {
+		s := before.Snapshot()
+		prev = &s
+	}
+	rev := models.ItemRevision{
+		ItemID:    after.ID,
+		Version:   after.Version,
+		Action:    action,
+		Actor:     identity.Actor(ctx),
+		ChangedAt: at,
+		Snapshot:  snapshot,
+		Diff:      models.DiffSnapshots(prev, &snapshot),
+	}
+	r.revisions[after.ID] = append(r.revisions[after.ID], rev)
+	r.events = append(r.events, models.ItemEvent{ID: int64(len(r.events)) + 1, Tenant: r.owners[after.ID], ItemRevision: rev})
+	r.enqueueWebhooks(action, after, at)
+}
+
+// publish passes the events recorded since mark to the listeners. Write
+// methods defer it right after locking r.mu so listeners only see writes
+// that were not rolled back.
+func (r *MemoryItemRepository) publish(mark int) {
+	for _, e := range r.events[mark:] {
+		for _, fn := range r.listeners {
+			fn(e)
+		}
+	}
+}
+
+func (r *MemoryItemRepository) EventsSince(ctx context.Context, afterID int64, limit int) ([]models.ItemEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Event ids are positions in r.events, starting at 1.
+	var events []models.ItemEvent
+	for _, e := range r.events[min(max(afterID, 0), int64(len(r.events))):] {
+		if len(events) == limit {
+			break
+		}
+		if inTenant(ctx, e.Tenant) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// Listen calls fn for every write until ctx is done. fn runs while the
+// store is locked, so it must not block or call back into the store.
+func (r *MemoryItemRepository) Listen(ctx context.Context, fn func(models.ItemEvent)) error {
+	r.mu.Lock()
+	id := r.nextListener
+	r.nextListener++
+	r.listeners[id] = fn
+	r.mu.Unlock()
+
+	<-ctx.Done()
+
+	r.mu.Lock()
+	delete(r.listeners, id)
+	r.mu.Unlock()
+	return nil
+}
+
+// Ping always succeeds; it lets the memory store back the readiness probe.
+func (r *MemoryItemRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+// matchesFilter applies the same predicates as buildItemFilter.
+func matchesFilter(i *models.Item, f *models.ItemFilter) bool {
+	switch f.Deleted {
+	case models.DeletedExclude:
+		if i.DeletedAt != nil {
+			return false
+		}
+	case models.DeletedOnly:
+		if i.DeletedAt == nil {
+			return false
+		}
+	}
+	name := strings.ToLower(i.Name)
+	if f.Name != "" && !strings.Contains(name, strings.ToLower(f.Name)) {
+		return false
+	}
+	if f.NamePrefix != "" && !strings.HasPrefix(name, strings.ToLower(f.NamePrefix)) {
+		return false
+	}
+	if f.Currency != "" && i.Currency != f.Currency {
+		if _, ok := i.Prices[f.Currency]; !ok {
+			return false
+		}
+	}
+	if f.MinPrice != nil && i.Price.Cmp(*f.MinPrice) < 0 {
+		return false
+	}
+	if f.MaxPrice != nil && i.Price.Cmp(*f.MaxPrice) > 0 {
+		return false
+	}
+	if f.CreatedAfter != nil && i.CreatedAt.Before(*f.CreatedAfter) {
+		return false
+	}
+	if f.CreatedBefore != nil && !i.CreatedAt.Before(*f.CreatedBefore) {
+		return false
+	}
+	if f.UpdatedAfter != nil && i.UpdatedAt.Before(*f.UpdatedAfter) {
+		return false
+	}
+	if f.UpdatedBefore != nil && !i.UpdatedAt.Before(*f.UpdatedBefore) {
+		return false
+	}
+	return true
+}
+
+// sortItems orders items the way buildOrderBy orders rows.
+func sortItems(items []models.Item, fields []models.SortField) {
+	order := effectiveSort(fields)
+	sort.SliceStable(items, func(a, b int) bool {
+		for _, f := range order {
+			c := compareColumn(&items[a], &items[b], f.Column)
+			if c == 0 {
+				continue
+			}
+			if f.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+func compareColumn(a, b *models.Item, column string) int {
+	switch column {
+	case "name":
+		return strings.Compare(a.Name, b.Name)
+	case "price":
+		return a.Price.Cmp(b.Price)
+	case "created_at":
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case "updated_at":
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	case "id":
+		return strings.Compare(a.ID.String(), b.ID.String())
+	}
+	return 0
+}
+
+// searchWords splits s into lower-case words the way the 'simple' text
+// search configuration does.
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// wordSimilarity approximates pg_trgm's word_similarity: the average over
+// the query terms of their best trigram similarity to a word of the name.
+func wordSimilarity(terms map[string]bool, words []string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	var total float64
+	for t := range terms {
+		best := 0.0
+		for _, w := range words {
+			best = max(best, trigramSimilarity(t, w))
+		}
+		total += best
+	}
+	return total / float64(len(terms))
+}
+
+// trigramSimilarity is pg_trgm's similarity() for two single words: the
+// share of distinct trigrams, padded with two leading and one trailing
+// space, that the words have in common.
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	common := 0
+	for t := range ta {
+		if tb[t] {
+			common++
+		}
+	}
+	union := len(ta) + len(tb) - common
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+func trigrams(word string) map[string]bool {
+	runes := []rune("  " + word + " ")
+	out := make(map[string]bool, len(runes))
+	for n := 0; n+3 <= len(runes); n++ {
+		out[string(runes[n:n+3])] = true
+	}
+	return out
+}
+
+// highlightWords marks the words of name that are query terms, as
+// ts_headline does with HighlightAll, and renders the result.
+func highlightWords(name string, terms map[string]bool) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for n := 0; n < len(runes); {
+		end := n
+		for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) {
+			end++
+		}
+		if end == n {
+			b.WriteRune(runes[n])
+			n++
+			continue
+		}
+		word := string(runes[n:end])
+		if terms[strings.ToLower(word)] {
+			b.WriteString(models.MatchStart + word + models.MatchStop)
+		} else {
+			b.WriteString(word)
+		}
+		n = end
+	}
+	return models.RenderHighlight(b.String())
+}
+
+// clonePrices copies a price list so stored items never alias caller maps.
+func clonePrices(prices map[string]models.Money) map[string]models.Money {
+	if len(prices) == 0 {
+		return nil
+	}
+	return maps.Clone(prices)
+}
@@ -0,0 +1,628 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/identity"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+)
+
+func TestMemoryItemRepository_CRUD(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryItemRepository()
+
+	created, err := repo.Create(ctx, &models.CreateItemDTO{Name: "Widget", Price: models.MustParseMoney("9.99")})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.ID == uuid.Nil {
+		t.Fatal("expected generated id")
+	}
+	if !created.CreatedAt.Equal(created.UpdatedAt) {
+		t.Errorf("expected created_at == updated_at on create, got %v and %v", created.CreatedAt, created.UpdatedAt)
+	}
+
+	got, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Name != "Widget" || !got.Price.Equal(models.MustParseMoney("9.99")) {
+		t.Errorf("unexpected item %+v", got)
+	}
+
+	name := "Gadget"
+	updated, err := repo.Update(ctx, created.ID, &models.UpdateItemDTO{Name: &name}, 0)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.Name != "Gadget" || !updated.Price.Equal(models.MustParseMoney("9.99")) {
+		t.Errorf("unexpected updated item %+v", updated)
+	}
+	if updated.UpdatedAt.Before(created.UpdatedAt) {
+		t.Errorf("updated_at went backwards")
+	}
+
+	if err := repo.Delete(ctx, created.ID, 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, created.ID); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("expected ErrItemNotFound after delete, got %v", err)
+	}
+}
+
+func TestMemoryItemRepository_NotFound(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryItemRepository()
+	id := uuid.New()
+
+	if _, err := repo.GetByID(ctx, id); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("get: expected ErrItemNotFound, got %v", err)
+	}
+	if _, err := repo.Update(ctx, id, &models.UpdateItemDTO{}, 0); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("update: expected ErrItemNotFound, got %v", err)
+	}
+	if err := repo.Delete(ctx, id, 0); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("delete: expected ErrItemNotFound, got %v", err)
+	}
+}
+
+func TestMemoryItemRepository_GetAllOrderingAndPagination(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryItemRepository()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tick := 0
+	repo.now = func() time.Time {
+		tick++
+		return base.Add(time.Duration(tick) * time.Second)
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := repo.Create(ctx, &models.CreateItemDTO{Name: fmt.Sprintf("item-%d", i), Price: models.MustParseMoney("1")}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	items, total, err := repo.GetAll(ctx, &models.ItemListQuery{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("get all: %v", err)
+	}
+	if total != 5 {
+		t.Errorf("expected total 5, got %d", total)
+	}
+	if len(items) != 2 || items[0].Name != "item-3" || items[1].Name != "item-2" {
+		t.Errorf("expected newest-first page [item-3 item-2], got %+v", items)
+	}
+
+	items, total, err = repo.GetAll(ctx, &models.ItemListQuery{Limit: 10, Offset: 10})
+	if err != nil {
+		t.Fatalf("get all: %v", err)
+	}
+	if total != 5 || len(items) != 0 {
+		t.Errorf("expected empty page with total 5, got %d items, total %d", len(items), total)
+	}
+}
+
+func TestMemoryItemRepository_ConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryItemRepository()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			item, err := repo.Create(ctx, &models.CreateItemDTO{Name: "x", Price: models.MustParseMoney("1")})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			price := models.MustParseMoney("2.0")
+			repo.Update(ctx, item.ID, &models.UpdateItemDTO{Price: &price}, 0)
+			repo.GetAll(ctx, &models.ItemListQuery{Limit: 10})
+		}()
+	}
+	wg.Wait()
+
+	if _, total, _ := repo.GetAll(ctx, &models.ItemListQuery{Limit: 1}); total != 50 {
+		t.Errorf("expected 50 items, got %d", total)
+	}
+}
+
+func TestMemoryItemRepository_GetAllFilterAndSort(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryItemRepository()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tick := 0
+	repo.now = func() time.Time {
+		tick++
+		return base.Add(time.Duration(tick) * time.Hour)
+	}
+
+	for _, dto := range []models.CreateItemDTO{
+		{Name: "Red Widget", Price: models.MustParseMoney("5")},
+		{Name: "Blue Widget", Price: models.MustParseMoney("15")},
+		{Name: "Widget Pro", Price: models.MustParseMoney("25")},
+		{Name: "Gadget", Price: models.MustParseMoney("15")},
+	} {
+		if _, err := repo.Create(ctx, &dto); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	minPrice, maxPrice := models.MustParseMoney("10"), models.MustParseMoney("30")
+	items, total, err := repo.GetAll(ctx, &models.ItemListQuery{
+		Filter: models.ItemFilter{Name: "widget", MinPrice: &minPrice, MaxPrice: &maxPrice},
+		Sort:   []models.SortField{{Column: "price", Desc: true}},
+		Limit:  10,
+	})
+	if err != nil {
+		t.Fatalf("get all: %v", err)
+	}
+	if total != 2 || len(items) != 2 || items[0].Name != "Widget Pro" || items[1].Name != "Blue Widget" {
+		t.Errorf("unexpected filtered page (total %d): %+v", total, items)
+	}
+
+	items, _, _ = repo.GetAll(ctx, &models.ItemListQuery{
+		Filter: models.ItemFilter{NamePrefix: "widget"},
+		Limit:  10,
+	})
+	if len(items) != 1 || items[0].Name != "Widget Pro" {
+		t.Errorf("expected prefix match on Widget Pro, got %+v", items)
+	}
+
+	after := base.Add(2 * time.Hour)
+	items, _, _ = repo.GetAll(ctx, &models.ItemListQuery{
+		Filter: models.ItemFilter{CreatedAfter: &after},
+		Sort:   []models.SortField{{Column: "price"}, {Column: "name", Desc: true}},
+		Limit:  10,
+	})
+	var names []string
+	for _, i := range items {
+		names = append(names, i.Name)
+	}
+	if fmt.Sprint(names) != "[Gadget Blue Widget Widget Pro]" {
+		t.Errorf("unexpected multi-key order %v", names)
+	}
+}
+
+func TestMemoryItemRepository_GetPage(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryItemRepository()
+
+	// Every item shares one timestamp so ordering relies on the id tie-breaker.
+	repo.now = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+	for i := 0; i < 5; i++ {
+		if _, err := repo.Create(ctx, &models.CreateItemDTO{Name: fmt.Sprintf("item-%d", i), Price: models.MustParseMoney("1")}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	want, _, _ := repo.GetAll(ctx, &models.ItemListQuery{Limit: 10})
+
+	filter := &models.ItemFilter{}
+	page1, more, err := repo.GetPage(ctx, filter, nil, 2)
+	if err != nil || !more || len(page1) != 2 || page1[0].ID != want[0].ID {
+		t.Fatalf("unexpected first page %+v (more=%v, err=%v)", page1, more, err)
+	}
+
+	last := page1[1]
+	page2, more, _ := repo.GetPage(ctx, filter, &models.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}, 2)
+	if !more || len(page2) != 2 || page2[0].ID != want[2].ID || page2[1].ID != want[3].ID {
+		t.Fatalf("unexpected second page %+v", page2)
+	}
+
+	last = page2[1]
+	page3, more, _ := repo.GetPage(ctx, filter, &models.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}, 2)
+	if more || len(page3) != 1 || page3[0].ID != want[4].ID {
+		t.Fatalf("unexpected last page %+v (more=%v)", page3, more)
+	}
+
+	first := page3[0]
+	back, more, _ := repo.GetPage(ctx, filter, &models.Cursor{CreatedAt: first.CreatedAt, ID: first.ID, Backward: true}, 2)
+	if !more || len(back) != 2 || back[0].ID != want[2].ID || back[1].ID != want[3].ID {
+		t.Fatalf("unexpected backward page %+v (more=%v)", back, more)
+	}
+}
+
+func TestMemoryItemRepository_VersionConflict(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryItemRepository()
+
+	item, _ := repo.Create(ctx, &models.CreateItemDTO{Name: "Widget", Price: models.MustParseMoney("1")})
+	if item.Version != 1 {
+		t.Fatalf("expected version 1, got %d", item.Version)
+	}
+
+	price := models.MustParseMoney("2.0")
+	updated, err := repo.Update(ctx, item.ID, &models.UpdateItemDTO{Price: &price}, 1)
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("expected version 2, got %+v (%v)", updated, err)
+	}
+
+	if _, err := repo.Update(ctx, item.ID, &models.UpdateItemDTO{Price: &price}, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("update: expected ErrVersionConflict, got %v", err)
+	}
+	if err := repo.Delete(ctx, item.ID, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("delete: expected ErrVersionConflict, got %v", err)
+	}
+	if err := repo.Delete(ctx, item.ID, 2); err != nil {
+		t.Errorf("delete: unexpected error %v", err)
+	}
+}
+
+func TestMemoryItemRepository_Currencies(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryItemRepository()
+
+	eur, _ := repo.Create(ctx, &models.CreateItemDTO{Name: "Euro item", Price: models.MustParseMoney("5"), Currency: "EUR"})
+	usd, _ := repo.Create(ctx, &models.CreateItemDTO{
+		Name:     "Dollar item",
+		Price:    models.MustParseMoney("6"),
+		Currency: "USD",
+		Prices:   map[string]models.Money{"EUR": models.MustParseMoney("5.50")},
+	})
+	repo.Create(ctx, &models.CreateItemDTO{Name: "Pound item", Price: models.MustParseMoney("4"), Currency: "GBP"})
+
+	items, total, _ := repo.GetAll(ctx, &models.ItemListQuery{Filter: models.ItemFilter{Currency: "EUR"}, Limit: 10})
+	if total != 2 {
+		t.Fatalf("expected 2 items sold in EUR, got %d: %+v", total, items)
+	}
+
+	// Switching the base currency to one in the price list drops that entry.
+	currency := "EUR"
+	updated, err := repo.Update(ctx, usd.ID, &models.UpdateItemDTO{Currency: &currency}, 0)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.Currency != "EUR" || len(updated.Prices) != 0 {
+		t.Errorf("unexpected updated item %+v", updated)
+	}
+
+	prices := map[string]models.Money{"CHF": models.MustParseMoney("5.25")}
+	updated, _ = repo.Update(ctx, eur.ID, &models.UpdateItemDTO{Prices: &prices}, 0)
+	prices["CHF"] = models.MustParseMoney("0")
+	if got := updated.Prices["CHF"]; !got.Equal(models.MustParseMoney("5.25")) {
+		t.Errorf("stored price list aliases the caller's map: %v", updated.Prices)
+	}
+
+	// A prices-only update cannot repeat the stored base currency.
+	prices = map[string]models.Money{"EUR": models.MustParseMoney("9"), "CHF": models.MustParseMoney("5.25")}
+	_, err = repo.Update(ctx, eur.ID, &models.UpdateItemDTO{Prices: &prices}, 0)
+	var invalid models.ValidationErrors
+	if !errors.As(err, &invalid) || invalid[0].Field != "prices.EUR" {
+		t.Fatalf("expected prices.EUR to be rejected, got %v", err)
+	}
+	if got, _ := repo.GetByID(ctx, eur.ID); got.Version != updated.Version {
+		t.Errorf("expected the rejected update to change nothing, got version %d", got.Version)
+	}
+}
+
+func TestMemoryItemRepository_History(t *testing.T) {
+	ctx := identity.WithIdentity(context.Background(), &identity.Identity{Subject: "alice"})
+	repo := NewMemoryItemRepository()
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+
+	created, err := repo.Create(ctx, &models.CreateItemDTO{Name: "Widget", Price: models.MustParseMoney("1.00")})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	name := "Gadget"
+	if _, err := repo.Update(ctx, created.ID, &models.UpdateItemDTO{Name: &name}, 0); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	history, err := repo.History(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 2 || history[0].Action != models.ActionUpdated || history[1].Action != models.ActionCreated {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	if history[0].Actor != "alice" {
+		t.Errorf("expected actor alice, got %q", history[0].Actor)
+	}
+	if _, ok := history[0].Diff["name"]; !ok || len(history[0].Diff) != 1 {
+		t.Errorf("expected name-only diff, got %v", history[0].Diff)
+	}
+
+	old, err := repo.GetAsOf(ctx, created.ID, created.CreatedAt)
+	if err != nil {
+		t.Fatalf("as of: %v", err)
+	}
+	if old.Name != "Widget" || old.Version != 1 {
+		t.Errorf("expected version 1 named Widget, got %+v", old)
+	}
+	if _, err := repo.GetAsOf(ctx, created.ID, created.CreatedAt.Add(-time.Second)); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("expected not found before creation, got %v", err)
+	}
+
+	reverted, err := repo.Revert(ctx, created.ID, 1, 2)
+	if err != nil {
+		t.Fatalf("revert: %v", err)
+	}
+	if reverted.Name != "Widget" || reverted.Version != 3 {
+		t.Errorf("expected version 3 named Widget, got %+v", reverted)
+	}
+	if _, err := repo.Revert(ctx, created.ID, 1, 2); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected version conflict, got %v", err)
+	}
+	if _, err := repo.Revert(ctx, created.ID, 9, 0); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("expected revision not found, got %v", err)
+	}
+
+	if err := repo.Delete(ctx, created.ID, 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := repo.GetAsOf(ctx, created.ID, clock); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("expected deleted item hidden as of now, got %v", err)
+	}
+}
+
+func TestMemoryItemRepository_Bulk(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryItemRepository()
+
+	existing, err := repo.Create(ctx, &models.CreateItemDTO{Name: "Widget", Price: models.MustParseMoney("1.00"), Currency: "USD"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	name := "Gadget"
+	ops := []models.BulkOperation{
+		{Op: models.BulkCreate, Create: &models.CreateItemDTO{Name: "New", Price: models.MustParseMoney("2.00"), Currency: "USD"}},
+		{Op: models.BulkUpdate, ID: existing.ID, Update: &models.UpdateItemDTO{Name: &name}},
+		{Op: models.BulkDelete, ID: uuid.New()},
+	}
+
+	_, err = repo.Bulk(ctx, ops, true)
+	var bulkErr *BulkError
+	if !errors.As(err, &bulkErr) || bulkErr.Index != 2 || !errors.Is(err, ErrItemNotFound) {
+		t.Fatalf("expected operation 2 to abort the batch, got %v", err)
+	}
+	if _, total, _ := repo.GetAll(ctx, &models.ItemListQuery{Limit: 10}); total != 1 {
+		t.Errorf("expected atomic batch rolled back, got %d items", total)
+	}
+	if got, _ := repo.GetByID(ctx, existing.ID); got.Name != "Widget" || got.Version != 1 {
+		t.Errorf("expected update rolled back, got %+v", got)
+	}
+
+	outcomes, err := repo.Bulk(ctx, ops, false)
+	if err != nil {
+		t.Fatalf("best effort: %v", err)
+	}
+	if outcomes[0].Err != nil || outcomes[1].Err != nil || !errors.Is(outcomes[2].Err, ErrItemNotFound) {
+		t.Fatalf("unexpected outcomes %+v", outcomes)
+	}
+	if outcomes[1].Item.Name != "Gadget" {
+		t.Errorf("expected updated item, got %+v", outcomes[1].Item)
+	}
+	if _, total, _ := repo.GetAll(ctx, &models.ItemListQuery{Limit: 10}); total != 2 {
+		t.Errorf("expected 2 items after best-effort batch, got %d", total)
+	}
+}
+
+func TestMemoryItemRepository_Search(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryItemRepository()
+	for _, name := range []string{"Blue Widget", "Widgets Deluxe", "Red Gadget", "Trashed Widget"} {
+		if _, err := repo.Create(ctx, &models.CreateItemDTO{Name: name, Price: models.MustParseMoney("1.00"), Currency: "USD"}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	page, _, _ := repo.GetAll(ctx, &models.ItemListQuery{Filter: models.ItemFilter{Name: "Trashed"}, Limit: 1})
+	if err := repo.Delete(ctx, page[0].ID, 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	results, err := repo.Search(ctx, &models.ItemSearchQuery{Text: "widget", Limit: 10})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(results) != 2 || results[0].Name != "Blue Widget" || results[1].Name != "Widgets Deluxe" {
+		t.Fatalf("expected exact match ranked above plural, got %+v", results)
+	}
+	if results[0].Highlight != "Blue <mark>Widget</mark>" || results[1].Highlight != "Widgets Deluxe" {
+		t.Errorf("unexpected highlights %q, %q", results[0].Highlight, results[1].Highlight)
+	}
+	if results[0].Rank <= results[1].Rank {
+		t.Errorf("expected descending rank, got %v then %v", results[0].Rank, results[1].Rank)
+	}
+
+	results, _ = repo.Search(ctx, &models.ItemSearchQuery{Text: "gadgett", Limit: 10})
+	if len(results) != 1 || results[0].Name != "Red Gadget" {
+		t.Errorf("expected typo to match Red Gadget, got %+v", results)
+	}
+
+	results, _ = repo.Search(ctx, &models.ItemSearchQuery{Text: "widget", Filter: models.ItemFilter{Deleted: models.DeletedOnly}, Limit: 10})
+	if len(results) != 1 || results[0].Name != "Trashed Widget" {
+		t.Errorf("expected filter to apply, got %+v", results)
+	}
+}
+
+func TestMemoryItemRepository_Events(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo := NewMemoryItemRepository()
+
+	var mu sync.Mutex
+	var seen []int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		repo.Listen(ctx, func(e models.ItemEvent) {
+			mu.Lock()
+			seen = append(seen, e.ID)
+			mu.Unlock()
+		})
+	}()
+	for {
+		repo.mu.RLock()
+		n := len(repo.listeners)
+		repo.mu.RUnlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	item, _ := repo.Create(ctx, &models.CreateItemDTO{Name: "Widget", Price: models.MustParseMoney("1.00"), Currency: "USD"})
+	ops := []models.BulkOperation{
+		{Op: models.BulkDelete, ID: item.ID},
+		{Op: models.BulkDelete, ID: uuid.New()},
+	}
+	if _, err := repo.Bulk(ctx, ops, true); err == nil {
+		t.Fatal("expected the atomic batch to fail")
+	}
+	if err := repo.Delete(ctx, item.ID, 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	cancel()
+	<-done
+	if len(seen) != 2 || seen[0] != 1 || seen[1] != 2 {
+		t.Errorf("expected only committed events 1 and 2, got %v", seen)
+	}
+
+	events, err := repo.EventsSince(context.Background(), 1, 10)
+	if err != nil {
+		t.Fatalf("events since: %v", err)
+	}
+	if len(events) != 1 || events[0].ID != 2 || events[0].Action != models.ActionDeleted {
+		t.Errorf("unexpected events %+v", events)
+	}
+}
+
+func TestMemoryItemRepository_APIKeys(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryItemRepository()
+
+	key, err := repo.CreateAPIKey(ctx, &models.APIKey{Name: "sync", Prefix: "ik_0000aaaa", Hash: []byte{1, 2, 3}, Scopes: []string{"items:read"}, CreatedBy: "ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := repo.APIKeyByPrefix(ctx, "ik_0000aaaa")
+	if err != nil || found.ID != key.ID || string(found.Hash) != string([]byte{1, 2, 3}) {
+		t.Fatalf("expected to find the key by prefix, got %+v, %v", found, err)
+	}
+	if _, err := repo.APIKeyByPrefix(ctx, "ik_ffffffff"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("expected ErrAPIKeyNotFound, got %v", err)
+	}
+
+	// Uses within a minute of the recorded one are not written.
+	first := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{first, first.Add(30 * time.Second)} {
+		if err := repo.TouchAPIKey(ctx, key.ID, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	found, _ = repo.APIKeyByPrefix(ctx, "ik_0000aaaa")
+	if found.LastUsedAt == nil || !found.LastUsedAt.Equal(first) {
+		t.Fatalf("expected the first use to be recorded, got %v", found.LastUsedAt)
+	}
+	repo.TouchAPIKey(ctx, key.ID, first.Add(2*time.Minute))
+	found, _ = repo.APIKeyByPrefix(ctx, "ik_0000aaaa")
+	if !found.LastUsedAt.Equal(first.Add(2 * time.Minute)) {
+		t.Fatalf("expected a later use to be recorded, got %v", found.LastUsedAt)
+	}
+
+	if err := repo.DeleteAPIKey(ctx, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteAPIKey(ctx, key.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("expected ErrAPIKeyNotFound on second delete, got %v", err)
+	}
+	if keys, _ := repo.ListAPIKeys(ctx); len(keys) != 0 {
+		t.Fatalf("expected no keys, got %d", len(keys))
+	}
+}
+
+func TestMemoryItemRepository_Tenants(t *testing.T) {
+	repo := NewMemoryItemRepository()
+	acme := tenant.With(context.Background(), &tenant.Tenant{ID: "acme", MaxItems: 2})
+	beta := tenant.With(context.Background(), &tenant.Tenant{ID: "beta"})
+	price := models.MustParseMoney("1")
+	active, stolen := true, "stolen"
+
+	item, err := repo.Create(acme, &models.CreateItemDTO{Name: "anvil", Price: price})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.CreateWebhook(beta, &models.CreateWebhookDTO{URL: "https://beta.test/hook", Events: []string{"item.created"}, Active: &active})
+
+	if _, err := repo.GetByID(beta, item.ID); !errors.Is(err, ErrItemNotFound) {
+		t.Fatalf("expected another tenant's item to be hidden, got %v", err)
+	}
+	if _, err := repo.Update(beta, item.ID, &models.UpdateItemDTO{Name: &stolen}, 0); !errors.Is(err, ErrItemNotFound) {
+		t.Fatalf("expected another tenant's item not to be updated, got %v", err)
+	}
+	if _, total, _ := repo.GetAll(beta, &models.ItemListQuery{Limit: 10}); total != 0 {
+		t.Fatalf("expected beta to list no items, got %d", total)
+	}
+	if events, _ := repo.EventsSince(beta, 0, 10); len(events) != 0 {
+		t.Fatalf("expected beta to see no events, got %d", len(events))
+	}
+	if events, _ := repo.EventsSince(tenant.System(beta), 0, 10); len(events) != 1 || events[0].Tenant != "acme" {
+		t.Fatalf("expected the system to see acme's event, got %+v", events)
+	}
+	if len(repo.deliveries) != 0 {
+		t.Fatalf("expected beta's webhook not to hear about acme's item, got %d deliveries", len(repo.deliveries))
+	}
+
+	if _, err := repo.Create(acme, &models.CreateItemDTO{Name: "hammer", Price: price}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Create(acme, &models.CreateItemDTO{Name: "tongs", Price: price}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if _, err := repo.Import(acme, []models.CreateItemDTO{{Name: "tongs", Price: price}}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded on import, got %v", err)
+	}
+	// Trashed items do not count, but restoring one must fit the quota.
+	repo.Delete(acme, item.ID, 0)
+	if _, err := repo.Create(acme, &models.CreateItemDTO{Name: "tongs", Price: price}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Restore(acme, item.ID); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded on restore, got %v", err)
+	}
+	if _, err := repo.Create(beta, &models.CreateItemDTO{Name: "tongs", Price: price}); err != nil {
+		t.Fatalf("expected beta to be unaffected by acme's quota, got %v", err)
+	}
+}
+
+func TestMemoryItemRepository_ItemStats(t *testing.T) {
+	repo := NewMemoryItemRepository()
+	now := time.Now()
+	repo.now = func() time.Time { return now }
+	acme := tenant.With(context.Background(), &tenant.Tenant{ID: "acme"})
+	price := models.MustParseMoney("1")
+
+	old, _ := repo.Create(acme, &models.CreateItemDTO{Name: "old", Price: price})
+	now = now.Add(2 * time.Minute)
+	repo.Create(acme, &models.CreateItemDTO{Name: "new", Price: price})
+	repo.Create(context.Background(), &models.CreateItemDTO{Name: "other", Price: price})
+	repo.Delete(acme, old.ID, 0)
+
+	s, err := repo.ItemStats(acme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *s != (models.ItemStats{Live: 1, Trashed: 1, CreatedLastMinute: 1, DeletedLastMinute: 1}) {
+		t.Fatalf("unexpected acme stats %+v", s)
+	}
+	if s, _ := repo.ItemStats(tenant.System(acme)); s.Live != 2 || s.CreatedLastMinute != 2 {
+		t.Fatalf("unexpected stats across tenants %+v", s)
+	}
+}
@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+)
+
+// enqueueWebhooks queues the event announcing a revision of item for every
+// webhook of the item's tenant subscribed to it. Callers must hold r.mu for
+// writing.
+func (r *MemoryItemRepository) enqueueWebhooks(action string, item *models.Item, at time.Time) {
+	event := models.NewWebhookEvent(action, item, at)
+	var payload json.RawMessage
+	for _, h := range r.webhooks {
+		if !h.Subscribed(event.Type) || r.owners[h.ID] != r.owners[item.ID] {
+			continue
+		}
+		if payload == nil {
+			payload, _ = json.Marshal(event)
+		}
+		r.queueDelivery(h.ID, event.ID, event.Type, payload, at)
+	}
+}
+
+// queueDelivery stores a new pending delivery. Callers must hold r.mu for
+// writing.
+func (r *MemoryItemRepository) queueDelivery(webhookID, eventID uuid.UUID, eventType string, payload json.RawMessage, at time.Time) models.WebhookDelivery {
+	r.lastDelivery++
+	d := models.WebhookDelivery{
+		ID:            r.lastDelivery,
+		WebhookID:     webhookID,
+		EventID:       eventID,
+		EventType:     eventType,
+		Payload:       payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &at,
+		CreatedAt:     at,
+	}
+	r.deliveries[d.ID] = d
+	return d
+}
+
+func (r *MemoryItemRepository) CreateWebhook(ctx context.Context, dto *models.CreateWebhookDTO) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.timestamp()
+	h := models.Webhook{
+		ID:        uuid.New(),
+		URL:       dto.URL,
+		Events:    slices.Clone(dto.Events),
+		Secret:    dto.Secret,
+		Active:    *dto.Active,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	r.webhooks[h.ID] = h
+	r.owners[h.ID] = tenant.ID(ctx)
+	return &h, nil
+}
+
+func (r *MemoryItemRepository) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hooks := make([]models.Webhook, 0, len(r.webhooks))
+	for id, h := range r.webhooks {
+		if r.visible(ctx, id) {
+			hooks = append(hooks, h)
+		}
+	}
+	sort.Slice(hooks, func(a, b int) bool {
+		if c := hooks[a].CreatedAt.Compare(hooks[b].CreatedAt); c != 0 {
+			return c < 0
+		}
+		return hooks[a].ID.String() < hooks[b].ID.String()
+	})
+	return hooks, nil
+}
+
+func (r *MemoryItemRepository) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	h, ok := r.webhooks[id]
+	if !ok || !r.visible(ctx, id) {
+		return nil, ErrWebhookNotFound
+	}
+	return &h, nil
+}
+
+func (r *MemoryItemRepository) UpdateWebhook(ctx context.Context, id uuid.UUID, dto *models.UpdateWebhookDTO) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, ok := r.webhooks[id]
+	if !ok || !r.visible(ctx, id) {
+		return nil, ErrWebhookNotFound
+	}
+	if dto.URL != nil {
+		h.URL = *dto.URL
+	}
+	if dto.Events != nil {
+		h.Events = slices.Clone(*dto.Events)
+	}
+	if dto.Secret != nil {
+		h.Secret = *dto.Secret
+	}
+	if dto.Active != nil {
+		h.Active = *dto.Active
+	}
+	h.UpdatedAt = r.timestamp()
+	r.webhooks[id] = h
+	return &h, nil
+}
+
+func (r *MemoryItemRepository) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[id]; !ok || !r.visible(ctx, id) {
+		return ErrWebhookNotFound
+	}
+	delete(r.webhooks, id)
+	delete(r.owners, id)
+	for did, d := range r.deliveries {
+		if d.WebhookID == id {
+			delete(r.deliveries, did)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryItemRepository) Deliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.webhooks[webhookID]; !ok || !r.visible(ctx, webhookID) {
+		return nil, ErrWebhookNotFound
+	}
+	var out []models.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.WebhookID == webhookID {
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].ID > out[b].ID })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *MemoryItemRepository) Redeliver(ctx context.Context, webhookID uuid.UUID, deliveryID int64) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	orig, ok := r.deliveries[deliveryID]
+	if !ok || orig.WebhookID != webhookID || !r.visible(ctx, webhookID) {
+		return nil, ErrDeliveryNotFound
+	}
+	d := r.queueDelivery(webhookID, orig.EventID, orig.EventType, orig.Payload, r.timestamp())
+	return &d, nil
+}
+
+func (r *MemoryItemRepository) ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.PendingDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []models.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.Status == models.DeliveryPending && !d.NextAttemptAt.After(now) && r.webhooks[d.WebhookID].Active {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(a, b int) bool {
+		if c := due[a].NextAttemptAt.Compare(*due[b].NextAttemptAt); c != 0 {
+			return c < 0
+		}
+		return due[a].ID < due[b].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	pending := make([]models.PendingDelivery, len(due))
+	for n, d := range due {
+		lease := leaseUntil
+		d.NextAttemptAt = &lease
+		r.deliveries[d.ID] = d
+		h := r.webhooks[d.WebhookID]
+		pending[n] = models.PendingDelivery{WebhookDelivery: d, URL: h.URL, Secret: h.Secret}
+	}
+	return pending, nil
+}
+
+func (r *MemoryItemRepository) RecordAttempt(ctx context.Context, id int64, a *models.DeliveryAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.deliveries[id]
+	if !ok {
+		return nil
+	}
+	at := a.At
+	d.Attempts++
+	d.LastAttemptAt = &at
+	d.ResponseStatus = a.ResponseStatus
+	d.LastError = a.Error
+	d.Status, _ = deliveryOutcome(a)
+	if d.Status == models.DeliveryDelivered {
+		d.DeliveredAt = &at
+	}
+	d.NextAttemptAt = a.RetryAt
+	r.deliveries[id] = d
+	return nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gowthamd/go-crud-app/internal/ratelimit"
+	"github.com/jackc/pgx/v5"
+)
+
+// TakeToken applies l.Take to the bucket of key in a single statement, so
+// that replicas sharing the database share the bucket. Buckets are not
+// tenant data and bypass withTenant.
+func (r *ItemRepository) TakeToken(ctx context.Context, key string, l ratelimit.Limit, now time.Time) (time.Time, bool, error) {
+	query := `
+		INSERT INTO rate_limits AS b (key, full_at)
+		VALUES ($1, $2::timestamptz + $3 * interval '1 microsecond')
+		ON CONFLICT (key) DO UPDATE
+		SET full_at = GREATEST(b.full_at, $2) + $3 * interval '1 microsecond'
+		WHERE GREATEST(b.full_at, $2) + $3 * interval '1 microsecond' <= $2::timestamptz + $4 * interval '1 microsecond'
+		RETURNING full_at
+	`
+	var full time.Time
+	err := r.db.QueryRow(ctx, query, key, now, l.Interval().Microseconds(), l.Per.Microseconds()).Scan(&full)
+	if err == nil {
+		return full, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, false, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	// The bucket is empty; report when it fills up.
+	if err := r.db.QueryRow(ctx, `SELECT full_at FROM rate_limits WHERE key = $1`, key).Scan(&full); err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to read rate limit bucket: %w", err)
+	}
+	return full, false, nil
+}
+
+func (r *ItemRepository) SweepBuckets(ctx context.Context, now time.Time) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM rate_limits WHERE full_at <= $1`, now); err != nil {
+		return fmt.Errorf("failed to sweep rate limit buckets: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/ratelimit"
+)
+
+// ItemStore is the persistence contract used by the HTTP handlers.
+// ItemRepository (Postgres) and MemoryItemRepository both satisfy it.
+type ItemStore interface {
+	Create(ctx context.Context, item *models.CreateItemDTO) (*models.Item, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Item, error)
+	GetAll(ctx context.Context, query *models.ItemListQuery) ([]models.Item, int, error)
+	// Export calls fn for every item matching filter in sort order without
+	// loading them all at once.
+	Export(ctx context.Context, filter *models.ItemFilter, sort []models.SortField, fn func(*models.Item) error) error
+	// Search returns the items matching q.Filter whose name matches
+	// q.Text, best match first.
+	Search(ctx context.Context, q *models.ItemSearchQuery) ([]models.ItemSearchResult, error)
+	// GetPage returns up to limit items after cursor in keyset order (newest
+	// first) and whether more items exist in the direction of travel. A nil
+	// cursor starts at the newest item.
+	GetPage(ctx context.Context, filter *models.ItemFilter, cursor *models.Cursor, limit int) ([]models.Item, bool, error)
+	// Update and Delete are conditional on expectedVersion unless it is
+	// zero, returning ErrVersionConflict when the item has changed. Delete
+	// only moves the item to the trash.
+	Update(ctx context.Context, id uuid.UUID, updates *models.UpdateItemDTO, expectedVersion int64) (*models.Item, error)
+	Delete(ctx context.Context, id uuid.UUID, expectedVersion int64) error
+	// Restore takes a trashed item back out of the trash.
+	Restore(ctx context.Context, id uuid.UUID) (*models.Item, error)
+	// Purge permanently removes items trashed before cutoff and reports
+	// how many were removed.
+	Purge(ctx context.Context, cutoff time.Time) (int64, error)
+
+	// History lists an item's revisions, newest first.
+	History(ctx context.Context, id uuid.UUID) ([]models.ItemRevision, error)
+	// GetAsOf rebuilds an item as it was at the given instant.
+	GetAsOf(ctx context.Context, id uuid.UUID, at time.Time) (*models.Item, error)
+	// Revert restores the fields recorded at version as a new revision,
+	// conditional on expectedVersion unless it is zero.
+	Revert(ctx context.Context, id uuid.UUID, version, expectedVersion int64) (*models.Item, error)
+
+	// EventsSince lists up to limit change feed events with an id above
+	// afterID, oldest first. Ids follow the order writes began, not the
+	// order they committed, so a later call may see events below the
+	// highest id an earlier one returned.
+	EventsSince(ctx context.Context, afterID int64, limit int) ([]models.ItemEvent, error)
+	// Listen calls fn with every change feed event as its write commits,
+	// on this and every other replica, until ctx is done.
+	Listen(ctx context.Context, fn func(models.ItemEvent)) error
+
+	// Bulk applies validated operations all-or-nothing when atomic is set,
+	// failing with a *BulkError, or else independently of each other.
+	Bulk(ctx context.Context, ops []models.BulkOperation, atomic bool) ([]BulkOutcome, error)
+	// Import inserts validated items in one transaction and reports how
+	// many were written.
+	Import(ctx context.Context, items []models.CreateItemDTO) (int64, error)
+
+	// ItemStats counts the items of the tenant of ctx and its recent
+	// writes.
+	ItemStats(ctx context.Context) (*models.ItemStats, error)
+}
+
+// WebhookStore persists webhook subscriptions and their deliveries.
+// Deliveries are queued by the ItemStore in the same transaction as the
+// change they announce.
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, dto *models.CreateWebhookDTO) (*models.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, id uuid.UUID, dto *models.UpdateWebhookDTO) (*models.Webhook, error)
+	// DeleteWebhook removes a webhook along with its delivery log.
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+
+	// Deliveries lists up to limit deliveries of a webhook, newest first.
+	Deliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]models.WebhookDelivery, error)
+	// Redeliver queues a fresh copy of one of the webhook's deliveries.
+	Redeliver(ctx context.Context, webhookID uuid.UUID, deliveryID int64) (*models.WebhookDelivery, error)
+	// ClaimDeliveries leases up to limit pending deliveries that are due at
+	// now until leaseUntil, so that no other replica sends them meanwhile.
+	ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.PendingDelivery, error)
+	// RecordAttempt stores the outcome of sending a claimed delivery.
+	RecordAttempt(ctx context.Context, id int64, attempt *models.DeliveryAttempt) error
+}
+
+// APIKeyStore persists API keys. Keys are stored by hash and looked up by
+// their unique prefix.
+type APIKeyStore interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	DeleteAPIKey(ctx context.Context, id uuid.UUID) error
+	APIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	// TouchAPIKey records that a key was used at at. Uses within a minute
+	// of the last recorded one are not written.
+	TouchAPIKey(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+var (
+	_ ItemStore    = (*ItemRepository)(nil)
+	_ ItemStore    = (*MemoryItemRepository)(nil)
+	_ WebhookStore = (*ItemRepository)(nil)
+	_ WebhookStore = (*MemoryItemRepository)(nil)
+	_ APIKeyStore  = (*ItemRepository)(nil)
+	_ APIKeyStore  = (*MemoryItemRepository)(nil)
+
+	_ ratelimit.Store = (*ItemRepository)(nil)
+)
@@ -0,0 +1,299 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gowthamd/go-crud-app/internal/models"
+	"github.com/gowthamd/go-crud-app/internal/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrDeliveryNotFound is returned when a webhook has no such delivery.
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+const webhookColumns = `id, url, events, secret, active, created_at, updated_at`
+
+func scanWebhook(row pgx.Row, h *models.Webhook) error {
+	return row.Scan(&h.ID, &h.URL, &h.Events, &h.Secret, &h.Active, &h.CreatedAt, &h.UpdatedAt)
+}
+
+const deliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.last_attempt_at, d.response_status, d.last_error, d.created_at, d.delivered_at`
+
+func scanDelivery(row pgx.Row, d *models.WebhookDelivery, extra ...any) error {
+	dest := append([]any{&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastAttemptAt, &d.ResponseStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt}, extra...)
+	return row.Scan(dest...)
+}
+
+// enqueueWebhooks queues the event announcing a revision of item for every
+// active webhook of the tenant subscribed to it, inside the transaction
+// making the change.
+func enqueueWebhooks(ctx context.Context, tx pgx.Tx, action string, item *models.Item) error {
+	event := models.NewWebhookEvent(action, item, time.Now().UTC())
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT id, $1, $2, $3 FROM webhooks
+		WHERE active AND $2 = ANY(events) AND tenant_id = current_setting('app.tenant_id')
+	`
+	if _, err := tx.Exec(ctx, query, event.ID, event.Type, payload); err != nil {
+		return fmt.Errorf("failed to queue webhooks: %w", err)
+	}
+	return nil
+}
+
+func (r *ItemRepository) CreateWebhook(ctx context.Context, dto *models.CreateWebhookDTO) (*models.Webhook, error) {
+	query := `
+		INSERT INTO webhooks (url, events, secret, active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + webhookColumns
+
+	var h models.Webhook
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		return scanWebhook(tx.QueryRow(ctx, query, dto.URL, dto.Events, dto.Secret, *dto.Active), &h)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return &h, nil
+}
+
+func (r *ItemRepository) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	var hooks []models.Webhook
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE `+tenantScope+` ORDER BY created_at, id`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var h models.Webhook
+			if err := scanWebhook(rows, &h); err != nil {
+				return err
+			}
+			hooks = append(hooks, h)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return hooks, nil
+}
+
+func (r *ItemRepository) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	var h models.Webhook
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		return getWebhook(ctx, tx, id, &h)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return &h, nil
+}
+
+func (r *ItemRepository) UpdateWebhook(ctx context.Context, id uuid.UUID, dto *models.UpdateWebhookDTO) (*models.Webhook, error) {
+	query := "UPDATE webhooks SET updated_at = NOW()"
+	args := []interface{}{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		query += fmt.Sprintf(", %s = $%d", column, len(args))
+	}
+
+	if dto.URL != nil {
+		set("url", *dto.URL)
+	}
+	if dto.Events != nil {
+		set("events", *dto.Events)
+	}
+	if dto.Secret != nil {
+		set("secret", *dto.Secret)
+	}
+	if dto.Active != nil {
+		set("active", *dto.Active)
+	}
+	args = append(args, id)
+	query += fmt.Sprintf(" WHERE id = $%d AND %s RETURNING %s", len(args), tenantScope, webhookColumns)
+
+	var h models.Webhook
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		return scanWebhook(tx.QueryRow(ctx, query, args...), &h)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return &h, nil
+}
+
+func (r *ItemRepository) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	var deleted int64
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND `+tenantScope, id)
+		deleted = commandTag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if deleted == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *ItemRepository) Deliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries d
+		WHERE d.webhook_id = $1
+		ORDER BY d.id DESC
+		LIMIT $2
+	`
+
+	var deliveries []models.WebhookDelivery
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		// Checking the webhook first scopes its deliveries to the tenant.
+		if err := getWebhook(ctx, tx, webhookID, &models.Webhook{}); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, query, webhookID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var d models.WebhookDelivery
+			if err := scanDelivery(rows, &d); err != nil {
+				return err
+			}
+			deliveries = append(deliveries, d)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// getWebhook loads a webhook of the current tenant.
+func getWebhook(ctx context.Context, tx pgx.Tx, id uuid.UUID, h *models.Webhook) error {
+	return scanWebhook(tx.QueryRow(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1 AND `+tenantScope, id), h)
+}
+
+func (r *ItemRepository) Redeliver(ctx context.Context, webhookID uuid.UUID, deliveryID int64) (*models.WebhookDelivery, error) {
+	query := `
+		INSERT INTO webhook_deliveries AS d (webhook_id, event_id, event_type, payload)
+		SELECT webhook_id, event_id, event_type, payload
+		FROM webhook_deliveries
+		WHERE id = $1 AND webhook_id = $2
+			AND EXISTS (SELECT 1 FROM webhooks WHERE id = $2 AND ` + tenantScope + `)
+		RETURNING ` + deliveryColumns
+
+	var d models.WebhookDelivery
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		return scanDelivery(tx.QueryRow(ctx, query, deliveryID, webhookID), &d)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to redeliver webhook: %w", err)
+	}
+	return &d, nil
+}
+
+// ClaimDeliveries leases due deliveries of every tenant by pushing their
+// next attempt to leaseUntil. SKIP LOCKED lets every replica claim
+// concurrently, and a delivery whose sender dies is picked up again once
+// the lease expires.
+func (r *ItemRepository) ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.PendingDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries d SET next_attempt_at = $2
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT p.id FROM webhook_deliveries p
+			JOIN webhooks a ON a.id = p.webhook_id
+			WHERE p.status = 'pending' AND p.next_attempt_at <= $1 AND a.active
+			ORDER BY p.next_attempt_at, p.id
+			LIMIT $3
+			FOR UPDATE OF p SKIP LOCKED
+		)
+		RETURNING ` + deliveryColumns + `, w.url, w.secret`
+
+	var pending []models.PendingDelivery
+	err := r.withTenant(tenant.System(ctx), func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, now, leaseUntil, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var p models.PendingDelivery
+			if err := scanDelivery(rows, &p.WebhookDelivery, &p.URL, &p.Secret); err != nil {
+				return err
+			}
+			pending = append(pending, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	return pending, nil
+}
+
+func (r *ItemRepository) RecordAttempt(ctx context.Context, id int64, a *models.DeliveryAttempt) error {
+	status, deliveredAt := deliveryOutcome(a)
+	query := `
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1, last_attempt_at = $2, response_status = $3, last_error = $4,
+			status = $5, next_attempt_at = $6, delivered_at = $7
+		WHERE id = $1
+	`
+	err := r.withTenant(tenant.System(ctx), func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, query, id, a.At, a.ResponseStatus, a.Error, status, a.RetryAt, deliveredAt)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+	return nil
+}
+
+// deliveryOutcome derives the delivery status an attempt leads to.
+func deliveryOutcome(a *models.DeliveryAttempt) (string, *time.Time) {
+	switch {
+	case a.Delivered:
+		return models.DeliveryDelivered, &a.At
+	case a.RetryAt != nil:
+		return models.DeliveryPending, nil
+	}
+	return models.DeliveryFailed, nil
+}
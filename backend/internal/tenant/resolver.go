@@ -0,0 +1,92 @@
+package tenant
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gowthamd/go-crud-app/internal/identity"
+	"github.com/gowthamd/go-crud-app/internal/problem"
+)
+
+// Header names the tenant of a request explicitly.
+const Header = "X-Tenant-ID"
+
+// Resolver works out the tenant of each request.
+type Resolver struct {
+	// BaseDomain, when set, makes the first label of hosts below it name
+	// the tenant: acme.items.example.com is tenant acme.
+	BaseDomain string
+	// MaxItems is the default item quota and Quotas overrides it per
+	// tenant; zero means no limit.
+	MaxItems int
+	Quotas   map[string]int
+	// CrossTenant reports whether an authenticated caller that is not
+	// bound to a tenant may act in the one the request names. When it is
+	// nil or false, such callers are confined to Default.
+	CrossTenant func(*identity.Identity) bool
+}
+
+// Middleware resolves the tenant from, in order, the caller's
+// credentials, the X-Tenant-ID header, the host's subdomain, or else
+// Default. The header and host are only honoured for anonymous requests,
+// which reach the API only when authentication is off, and for callers
+// allowed by CrossTenant; other callers are refused with 403 when they
+// name a tenant other than their own. It must run after authentication.
+func (res *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested := r.Header.Get(Header)
+		if requested == "" {
+			requested = res.subdomain(r.Host)
+		}
+		if requested != "" && !Valid(requested) {
+			problem.Error(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid tenant %q", requested))
+			return
+		}
+
+		id := requested
+		if id == "" {
+			id = Default
+		}
+		if caller, ok := identity.FromContext(r.Context()); ok {
+			own := caller.Tenant
+			if own == "" {
+				own = Default
+				if res.CrossTenant != nil && res.CrossTenant(caller) {
+					own = id
+				}
+			}
+			if requested != "" && requested != own {
+				problem.Error(w, r, http.StatusForbidden, fmt.Sprintf("Credentials are not valid for tenant %q", requested))
+				return
+			}
+			id = own
+		}
+
+		next.ServeHTTP(w, r.WithContext(With(r.Context(), res.tenant(id))))
+	})
+}
+
+func (res *Resolver) tenant(id string) *Tenant {
+	limit, ok := res.Quotas[id]
+	if !ok {
+		limit = res.MaxItems
+	}
+	return &Tenant{ID: id, MaxItems: limit}
+}
+
+// subdomain returns the label of host directly below BaseDomain, if any.
+func (res *Resolver) subdomain(host string) string {
+	if res.BaseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	label, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(res.BaseDomain))
+	if !ok || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
@@ -0,0 +1,95 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gowthamd/go-crud-app/internal/identity"
+)
+
+func TestResolver_Middleware(t *testing.T) {
+	res := &Resolver{BaseDomain: "items.test", MaxItems: 10, Quotas: map[string]int{"beta": 50}}
+	h := res.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ten := FromContext(r.Context())
+		w.Write([]byte(ten.ID + " " + strconv.Itoa(ten.MaxItems)))
+	}))
+
+	res.CrossTenant = func(id *identity.Identity) bool { return id.Subject == "operator" }
+
+	tests := []struct {
+		name   string
+		host   string
+		header string
+		bound  string
+		want   int
+		body   string
+	}{
+		{"default", "localhost:8000", "", "", http.StatusOK, "default 10"},
+		{"header", "localhost", "acme", "", http.StatusOK, "acme 10"},
+		{"subdomain", "Beta.Items.Test:443", "", "", http.StatusOK, "beta 50"},
+		{"header over subdomain", "beta.items.test", "acme", "", http.StatusOK, "acme 10"},
+		{"nested subdomain", "a.beta.items.test", "", "", http.StatusOK, "default 10"},
+		{"invalid", "localhost", "Acme!", "", http.StatusBadRequest, ""},
+		{"bound", "localhost", "", "acme", http.StatusOK, "acme 10"},
+		{"bound and matching", "acme.items.test", "acme", "acme", http.StatusOK, "acme 10"},
+		{"bound elsewhere", "localhost", "beta", "acme", http.StatusForbidden, ""},
+		{"bound elsewhere by host", "beta.items.test", "", "acme", http.StatusForbidden, ""},
+		{"unbound", "localhost", "", "-", http.StatusOK, "default 10"},
+		{"unbound naming default", "localhost", "default", "-", http.StatusOK, "default 10"},
+		{"unbound elsewhere", "localhost", "beta", "-", http.StatusForbidden, ""},
+		{"unbound elsewhere by host", "beta.items.test", "", "-", http.StatusForbidden, ""},
+		{"cross-tenant", "localhost", "beta", "*", http.StatusOK, "beta 50"},
+		{"cross-tenant by host", "beta.items.test", "", "*", http.StatusOK, "beta 50"},
+		{"cross-tenant default", "localhost", "", "*", http.StatusOK, "default 10"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = tt.host
+		if tt.header != "" {
+			req.Header.Set(Header, tt.header)
+		}
+		// bound is a tenant claim, "-" an unbound caller and "*" an
+		// unbound caller allowed to cross tenants.
+		switch tt.bound {
+		case "":
+		case "-":
+			req = req.WithContext(identity.WithIdentity(context.Background(), &identity.Identity{Subject: "s"}))
+		case "*":
+			req = req.WithContext(identity.WithIdentity(context.Background(), &identity.Identity{Subject: "operator"}))
+		default:
+			req = req.WithContext(identity.WithIdentity(context.Background(), &identity.Identity{Subject: "s", Tenant: tt.bound}))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, rec.Code)
+		}
+		if tt.want == http.StatusOK && rec.Body.String() != tt.body {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.body, rec.Body.String())
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	for id, want := range map[string]bool{
+		"acme":                  true,
+		"a-1":                   true,
+		"":                      false,
+		"*":                     false,
+		"-acme":                 false,
+		"Acme":                  false,
+		"a.b":                   false,
+		"a_b":                   false,
+		strings.Repeat("a", 63): true,
+		strings.Repeat("a", 64): false,
+	} {
+		if got := Valid(id); got != want {
+			t.Errorf("Valid(%q): expected %v, got %v", id, want, got)
+		}
+	}
+}
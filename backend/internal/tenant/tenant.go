@@ -0,0 +1,56 @@
+// Package tenant carries the catalog a request works on through its
+// context. Every item query is scoped to it.
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// Default is the tenant of requests that name none, and of every item
+// created before tenants were introduced.
+const Default = "default"
+
+// All is the pseudo tenant of background jobs such as the trash purge,
+// which work across every tenant. It is never resolved from a request.
+const All = "*"
+
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Valid reports whether id is a well-formed tenant id: up to 63 lower-case
+// letters, digits and hyphens, not starting with a hyphen.
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+// Tenant is the tenant a request works on.
+type Tenant struct {
+	ID string
+	// MaxItems caps the tenant's live items; zero means no limit.
+	MaxItems int
+}
+
+type contextKey struct{}
+
+func With(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant of ctx, falling back to Default without
+// a quota.
+func FromContext(ctx context.Context) *Tenant {
+	if t, ok := ctx.Value(contextKey{}).(*Tenant); ok && t != nil {
+		return t
+	}
+	return &Tenant{ID: Default}
+}
+
+// ID returns the id of the tenant of ctx.
+func ID(ctx context.Context) string {
+	return FromContext(ctx).ID
+}
+
+// System marks ctx as working across every tenant.
+func System(ctx context.Context) context.Context {
+	return With(ctx, &Tenant{ID: All})
+}
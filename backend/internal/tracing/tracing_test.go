@@ -0,0 +1,155 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// record installs a provider whose finished spans end up in the returned
+// recorder.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	rec := record(t)
+	var seen string
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		seen = TraceID(r.Context())
+	})
+	r.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /items/{id}" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("got span %q of kind %v", span.Name(), span.SpanKind())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" || seen != got {
+		t.Errorf("expected the caller's trace, got %s (handler saw %s)", got, seen)
+	}
+	if span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected the caller's span as parent, got %s", span.Parent().SpanID())
+	}
+	if span.Status().Code == codes.Error {
+		t.Error("expected a successful request not to be an error")
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	span = rec.Ended()[1]
+	if span.Parent().IsValid() {
+		t.Error("expected a request without traceparent to start a trace")
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("expected a 500 to be an error, got %v", span.Status())
+	}
+}
+
+func TestQueryTracer(t *testing.T) {
+	rec := record(t)
+	var qt QueryTracer
+
+	// Queries outside a trace are not recorded.
+	ctx := qt.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	qt.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	if n := len(rec.Ended()); n != 0 {
+		t.Fatalf("expected no span, got %d", n)
+	}
+
+	parent, span := otel.Tracer("test").Start(context.Background(), "request")
+	ctx = qt.TraceQueryStart(parent, nil, pgx.TraceQueryStartData{SQL: "\n\t\tSELECT id FROM items WHERE id = $1", Args: []any{"secret"}})
+	qt.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("boom")})
+	span.End()
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	query := spans[0]
+	if query.Name() != "SELECT" || query.SpanKind() != trace.SpanKindClient {
+		t.Errorf("got span %q of kind %v", query.Name(), query.SpanKind())
+	}
+	if query.Parent().SpanID() != span.SpanContext().SpanID() {
+		t.Error("expected the query to be a child of the request")
+	}
+	if query.Status().Code != codes.Error || len(query.Events()) == 0 {
+		t.Errorf("expected the error to be recorded, got %v", query.Status())
+	}
+	for _, kv := range query.Attributes() {
+		if strings.Contains(kv.Value.Emit(), "secret") {
+			t.Errorf("expected arguments to be left out, got %s=%s", kv.Key, kv.Value.Emit())
+		}
+	}
+}
+
+func TestOperation(t *testing.T) {
+	tests := map[string]string{
+		"SELECT 1":                   "SELECT",
+		"\n\t\tinsert into items":    "INSERT",
+		"WITH moved AS (DELETE ...)": "WITH",
+		"select(1)":                  "SELECT",
+		"  ":                         "query",
+	}
+	for sql, want := range tests {
+		if got := operation(sql); got != want {
+			t.Errorf("%q: expected %s, got %s", sql, want, got)
+		}
+	}
+}
+
+func TestSetup_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Setup(context.Background(), Options{ServiceName: "test", Exporter: ExporterFile, File: path, SampleRatio: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := tracer().Start(context.Background(), "offline")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`"Name":"offline"`)) || !bytes.Contains(data, []byte(span.SpanContext().TraceID().String())) {
+		t.Errorf("expected the span in the file, got %s", data)
+	}
+
+	if _, err := Setup(context.Background(), Options{Exporter: "zipkin"}); err == nil {
+		t.Error("expected an unknown exporter to be refused")
+	}
+}
@@ -0,0 +1 @@
+SELECT to_regclass('items') IS NOT NULL;
@@ -0,0 +1 @@
+SELECT to_regclass('item_prices') IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_item_prices_currency;
+DROP INDEX IF EXISTS idx_items_currency;
+DROP TABLE IF EXISTS item_prices;
+ALTER TABLE items DROP COLUMN IF EXISTS currency;
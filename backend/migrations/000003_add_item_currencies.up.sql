@@ -0,0 +1,11 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+CREATE TABLE IF NOT EXISTS item_prices (
+    item_id UUID NOT NULL REFERENCES items (id) ON DELETE CASCADE,
+    currency CHAR(3) NOT NULL,
+    price DECIMAL(10, 2) NOT NULL CHECK (price >= 0),
+    PRIMARY KEY (item_id, currency)
+);
+
+CREATE INDEX IF NOT EXISTS idx_items_currency ON items (currency);
+CREATE INDEX IF NOT EXISTS idx_item_prices_currency ON item_prices (currency);
@@ -0,0 +1 @@
+SELECT to_regclass('item_revisions') IS NOT NULL;
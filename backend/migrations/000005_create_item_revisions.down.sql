@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_item_revisions_item_changed_at;
+DROP TABLE IF EXISTS item_revisions;
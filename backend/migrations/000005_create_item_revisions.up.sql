@@ -0,0 +1,27 @@
+-- Revisions deliberately have no foreign key to items so history survives
+-- purging of trashed items.
+CREATE TABLE IF NOT EXISTS item_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    item_id UUID NOT NULL,
+    version BIGINT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    snapshot JSONB NOT NULL,
+    diff JSONB NOT NULL DEFAULT '{}',
+    UNIQUE (item_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_revisions_item_changed_at ON item_revisions (item_id, changed_at DESC);
+
+-- Seed a baseline revision for items that predate history tracking.
+INSERT INTO item_revisions (item_id, version, action, actor, changed_at, snapshot)
+SELECT i.id, i.version, 'created', 'system', COALESCE(i.updated_at, i.created_at, NOW()),
+    jsonb_build_object(
+        'name', i.name,
+        'price', i.price::text,
+        'currency', i.currency,
+        'prices', COALESCE((SELECT jsonb_object_agg(p.currency, p.price::text) FROM item_prices p WHERE p.item_id = i.id), '{}'::jsonb)
+    )
+FROM items i
+WHERE NOT EXISTS (SELECT 1 FROM item_revisions r WHERE r.item_id = i.id);
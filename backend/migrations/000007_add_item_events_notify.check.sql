@@ -0,0 +1 @@
+SELECT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'item_revisions_notify');
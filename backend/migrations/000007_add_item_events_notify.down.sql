@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS item_revisions_notify ON item_revisions;
+DROP FUNCTION IF EXISTS notify_item_event();
@@ -0,0 +1,14 @@
+-- Every revision is announced on the item_events channel so that each API
+-- replica can fan it out to its own change feed subscribers. The payload is
+-- just the revision id; listeners read the row itself.
+CREATE OR REPLACE FUNCTION notify_item_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('item_events', NEW.id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS item_revisions_notify ON item_revisions;
+CREATE TRIGGER item_revisions_notify
+    AFTER INSERT ON item_revisions
+    FOR EACH ROW EXECUTE FUNCTION notify_item_event();
@@ -0,0 +1 @@
+SELECT to_regclass('webhook_deliveries') IS NOT NULL;
@@ -0,0 +1 @@
+SELECT EXISTS (SELECT 1 FROM pg_policies WHERE schemaname = current_schema() AND tablename = 'webhook_deliveries' AND policyname = 'tenant_isolation');
@@ -0,0 +1,20 @@
+DROP POLICY IF EXISTS tenant_isolation ON webhook_deliveries;
+ALTER TABLE webhook_deliveries DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON item_prices;
+ALTER TABLE item_prices DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON api_keys;
+ALTER TABLE api_keys DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON webhooks;
+ALTER TABLE webhooks DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON item_revisions;
+ALTER TABLE item_revisions DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON items;
+ALTER TABLE items DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS idx_webhooks_tenant;
+DROP INDEX IF EXISTS idx_items_tenant_created_at;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE item_revisions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE items DROP COLUMN IF EXISTS tenant_id;
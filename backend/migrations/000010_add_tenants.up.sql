@@ -0,0 +1,57 @@
+-- Every request runs in a transaction that sets app.tenant_id to its
+-- tenant, or to '*' for background jobs working across tenants. Rows that
+-- existed before tenants belong to 'default'; new rows take the tenant of
+-- the transaction inserting them.
+ALTER TABLE items ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default'
+    CHECK (tenant_id NOT IN ('', '*'));
+ALTER TABLE items ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id');
+
+ALTER TABLE item_revisions ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default'
+    CHECK (tenant_id NOT IN ('', '*'));
+ALTER TABLE item_revisions ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id');
+
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default'
+    CHECK (tenant_id NOT IN ('', '*'));
+ALTER TABLE webhooks ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id');
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default'
+    CHECK (tenant_id NOT IN ('', '*'));
+ALTER TABLE api_keys ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id');
+
+CREATE INDEX IF NOT EXISTS idx_items_tenant_created_at ON items (tenant_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_webhooks_tenant ON webhooks (tenant_id);
+
+-- Row-level security backs the tenant predicates of the application's
+-- queries. FORCE applies it to the table owner too; superusers and
+-- BYPASSRLS roles are still exempt, so the service should connect as an
+-- ordinary role.
+ALTER TABLE items ENABLE ROW LEVEL SECURITY;
+ALTER TABLE items FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON items
+    USING (current_setting('app.tenant_id', true) IN ('*', tenant_id));
+
+ALTER TABLE item_revisions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE item_revisions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON item_revisions
+    USING (current_setting('app.tenant_id', true) IN ('*', tenant_id));
+
+ALTER TABLE webhooks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhooks FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON webhooks
+    USING (current_setting('app.tenant_id', true) IN ('*', tenant_id));
+
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON api_keys
+    USING (current_setting('app.tenant_id', true) IN ('*', tenant_id));
+
+-- Child rows are visible exactly when their parent is.
+ALTER TABLE item_prices ENABLE ROW LEVEL SECURITY;
+ALTER TABLE item_prices FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON item_prices
+    USING (EXISTS (SELECT 1 FROM items i WHERE i.id = item_id));
+
+ALTER TABLE webhook_deliveries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhook_deliveries FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON webhook_deliveries
+    USING (EXISTS (SELECT 1 FROM webhooks w WHERE w.id = webhook_id));
@@ -0,0 +1 @@
+SELECT to_regclass('rate_limits') IS NOT NULL;
@@ -0,0 +1,7 @@
+-- One row per rate limit bucket, holding the time the bucket will be full
+-- again. Rows whose time has passed are equivalent to missing ones and are
+-- swept periodically.
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limits (
+    key TEXT PRIMARY KEY,
+    full_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
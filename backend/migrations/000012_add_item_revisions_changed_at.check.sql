@@ -0,0 +1 @@
+SELECT to_regclass('idx_item_revisions_changed_at') IS NOT NULL;
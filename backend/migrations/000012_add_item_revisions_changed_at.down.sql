@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_item_revisions_changed_at;
@@ -0,0 +1,2 @@
+-- Supports the per-minute write counts reported as metrics.
+CREATE INDEX IF NOT EXISTS idx_item_revisions_changed_at ON item_revisions (changed_at);
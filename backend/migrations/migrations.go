@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL schema migrations into the binary.
+// Files are named <version>_<name>.up.sql and <version>_<name>.down.sql;
+// <version>_<name>.check.sql returns true when the schema already holds
+// the change, so that migrate baseline can verify it.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -38,10 +38,26 @@ function App() {
     fetchItems()
   }, [fetchItems])
 
-  const handleDelete = async (id) => {
+  // Refresh when anyone changes an item. EventSource reconnects on its own
+  // and resumes from the last event it received.
+  useEffect(() => {
+    const source = new EventSource(`${API_URL}/items/events`)
+    const actions = ['created', 'updated', 'deleted', 'restored', 'reverted']
+    actions.forEach((action) => source.addEventListener(action, fetchItems))
+    return () => source.close()
+  }, [API_URL, fetchItems])
+
+  const handleDelete = async (item) => {
     if (!confirm('Are you sure you want to delete this item?')) return
     try {
-      const res = await fetch(`${API_URL}/items/${id}`, { method: 'DELETE' })
+      const res = await fetch(`${API_URL}/items/${item.id}`, {
+        method: 'DELETE',
+        headers: { 'If-Match': `"${item.version}"` }
+      })
+      if (res.status === 412) {
+        fetchItems()
+        throw new Error('This item was changed by someone else. Please review it and try again.')
+      }
       if (!res.ok) throw new Error('Failed to delete item')
       showToast('Item deleted', 'success')
       fetchItems()
@@ -76,7 +92,10 @@ function App() {
       if (currentItem) {
         res = await fetch(`${API_URL}/items/${currentItem.id}`, {
           method: 'PUT',
-          headers: { 'Content-Type': 'application/json' },
+          headers: {
+            'Content-Type': 'application/json',
+            'If-Match': `"${currentItem.version}"`
+          },
           body: JSON.stringify(payload)
         })
       } else {
@@ -86,9 +105,14 @@ function App() {
           body: JSON.stringify(payload)
         })
       }
+      if (res.status === 412) {
+        fetchItems()
+        throw new Error('This item was changed by someone else. Please reopen it and try again.')
+      }
       if (!res.ok) {
-        const text = await res.text()
-        throw new Error(text || 'Failed to save item')
+        const problem = await res.json().catch(() => null)
+        const message = problem?.errors?.map((e) => e.message).join(', ') || problem?.detail
+        throw new Error(message || 'Failed to save item')
       }
       showToast(currentItem ? 'Item updated' : 'Item created', 'success')
       setIsModalOpen(false)
@@ -154,7 +178,7 @@ function App() {
                         <Pencil size={18} />
                       </button>
                       <button
-                        onClick={() => handleDelete(item.id)}
+                        onClick={() => handleDelete(item)}
                         className="icon-btn danger"
                       >
                         <Trash2 size={18} />
@@ -163,7 +187,7 @@ function App() {
                   </div>
                   <h3 className="item-name">{item.name}</h3>
                   <p className="item-price">
-                    ${item.price.toFixed(2)}
+                    {Number(item.price).toFixed(2)} {item.currency}
                   </p>
                 </div>
               ))}